@@ -0,0 +1,490 @@
+/*
+
+BitBuffer implementation.
+
+*/
+
+package bitio
+
+import (
+	"io"
+)
+
+// BitBuffer is an in-memory buffer of bits (analogous to bytes.Buffer) with
+// independent read and write cursors. Bits are written at the end of the buffer,
+// and read from the read cursor. Bits at arbitrary offsets can also be accessed
+// (and patched) using GetBit(), SetBit(), GetBits() and SetBits().
+//
+// Read data is not discarded, bit offsets always refer to the beginning of the buffer.
+//
+// BitBuffer implements BitWriter, and BitReader() returns a BitReader view of it.
+// For convenience, it also implements io.Reader, io.Writer, io.ByteReader and io.ByteWriter,
+// so it can also be used as the source of a Reader or the output of a Writer.
+//
+// The zero value is an empty buffer ready to use, using highest-bits-first order.
+type BitBuffer struct {
+	buf []byte // the bits, len(buf) is (n+7)/8, bits after the first n are zero
+	n   int64  // number of bits in buf
+	off int64  // read cursor, bit offset of the next read
+	lsb bool   // tells if least-significant-bit-first order is used
+
+	// TryError holds the first error occurred in TryXXX() methods.
+	TryError error
+
+	// TryPanic tells if TryXXX() methods panic when they store an error in TryError,
+	// so processing can be aborted early. Use Catch() to recover from the panic.
+	TryPanic bool
+}
+
+// NewBitBuffer returns a new BitBuffer holding the bits of buf (8 * len(buf) bits).
+// The new BitBuffer takes ownership of buf, the caller should not use it after this call.
+//
+// The returned BitBuffer uses highest-bits-first order, see NewBitBufferLSB()
+// for least-significant-bit-first order.
+func NewBitBuffer(buf []byte) *BitBuffer {
+	return &BitBuffer{buf: buf, n: int64(len(buf)) * 8}
+}
+
+// NewBitBufferLSB returns a new BitBuffer holding the bits of buf (8 * len(buf) bits),
+// which uses least-significant-bit-first order.
+// The new BitBuffer takes ownership of buf, the caller should not use it after this call.
+func NewBitBufferLSB(buf []byte) *BitBuffer {
+	b := NewBitBuffer(buf)
+	b.lsb = true
+	return b
+}
+
+// Len returns the number of bits in the buffer (both read and unread).
+func (b *BitBuffer) Len() int64 {
+	return b.n
+}
+
+// Bytes returns the content of the buffer: (Len()+7)/8 bytes, the unused bits
+// of the last byte are zero. The slice aliases the buffer content, it is valid
+// until the next modification of the buffer.
+func (b *BitBuffer) Bytes() []byte {
+	return b.buf
+}
+
+// Truncate discards all but the first n bits of the buffer.
+// The read cursor is moved to n if it is beyond that.
+// It panics if n is negative or greater than the length of the buffer.
+func (b *BitBuffer) Truncate(n int64) {
+	if n < 0 || n > b.n {
+		panic("bitio: truncation out of range")
+	}
+	b.buf = b.buf[:(n+7)/8]
+	if frac := uint8(n % 8); frac > 0 {
+		// Zero the unused bits of the last byte
+		if b.lsb {
+			b.buf[len(b.buf)-1] &= 1<<frac - 1
+		} else {
+			b.buf[len(b.buf)-1] &^= 0xff >> frac
+		}
+	}
+	b.n = n
+	if b.off > n {
+		b.off = n
+	}
+}
+
+// Reset resets the buffer to be empty, the underlying storage is kept for future writes.
+func (b *BitBuffer) Reset() {
+	b.buf = b.buf[:0]
+	b.n, b.off = 0, 0
+}
+
+// getBits returns the n bits at bit offset off as the lowest n bits of u.
+// Must only be called if off+n <= b.n.
+func (b *BitBuffer) getBits(off int64, n uint8) uint64 {
+	var p [9]byte
+	copy(p[:], b.buf[off/8:])
+	return extractBits(&p, uint8(off%8), n, b.lsb)
+}
+
+// setBits sets the n bits at bit offset off to the lowest n bits of u
+// (u must not have bits set at n or higher positions).
+// Must only be called if (off+n+7)/8 <= len(b.buf).
+func (b *BitBuffer) setBits(off int64, u uint64, n uint8) {
+	for n > 0 {
+		i, bit := off/8, uint8(off%8)
+		k := 8 - bit // number of bits going into the byte at i
+		if k > n {
+			k = n
+		}
+		mask := byte(1<<k - 1)
+		if b.lsb {
+			b.buf[i] = b.buf[i]&^(mask<<bit) | byte(u)&mask<<bit
+			u >>= k
+		} else {
+			shift := 8 - bit - k
+			b.buf[i] = b.buf[i]&^(mask<<shift) | byte(u>>(n-k))&mask<<shift
+		}
+		off += int64(k)
+		n -= k
+	}
+}
+
+// checkRange checks if n bits at bit offset off are in the buffer.
+func (b *BitBuffer) checkRange(off int64, n uint8) error {
+	if n > 64 {
+		return ErrInvalidBitCount
+	}
+	if off < 0 || off+int64(n) > b.n {
+		return ErrInvalidParameter
+	}
+	return nil
+}
+
+// GetBits returns the n bits at bit offset off as the lowest n bits of u,
+// without moving the read cursor. n must not be greater than 64, else ErrInvalidBitCount is returned.
+// If the bits are not in the buffer, ErrInvalidParameter is returned.
+func (b *BitBuffer) GetBits(off int64, n uint8) (u uint64, err error) {
+	if err = b.checkRange(off, n); err != nil {
+		return 0, wrapError("GetBits", int64(n), off, err)
+	}
+	return b.getBits(off, n), nil
+}
+
+// GetBit returns the bit at bit offset off, true if it is 1.
+// If the bit is not in the buffer, ErrInvalidParameter is returned.
+func (b *BitBuffer) GetBit(off int64) (bit bool, err error) {
+	if err = b.checkRange(off, 1); err != nil {
+		return false, wrapError("GetBit", 1, off, err)
+	}
+	return b.getBits(off, 1) == 1, nil
+}
+
+// SetBits sets the n bits at bit offset off to the n lowest bits of u,
+// e.g. to patch a length field written earlier. Bits of u in positions higher than n are ignored.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+// If the bits are not in the buffer, ErrInvalidParameter is returned.
+func (b *BitBuffer) SetBits(off int64, u uint64, n uint8) (err error) {
+	if err = b.checkRange(off, n); err != nil {
+		return wrapError("SetBits", int64(n), off, err)
+	}
+	b.setBits(off, u&(1<<n-1), n)
+	return nil
+}
+
+// SetBit sets the bit at bit offset off: to 1 if bit is true, 0 otherwise.
+// If the bit is not in the buffer, ErrInvalidParameter is returned.
+func (b *BitBuffer) SetBit(off int64, bit bool) (err error) {
+	if err = b.checkRange(off, 1); err != nil {
+		return wrapError("SetBit", 1, off, err)
+	}
+	var u uint64
+	if bit {
+		u = 1
+	}
+	b.setBits(off, u, 1)
+	return nil
+}
+
+// Write appends len(p) bytes (8 * len(p) bits) to the buffer. It never fails.
+//
+// Write implements io.Writer.
+func (b *BitBuffer) Write(p []byte) (n int, err error) {
+	if b.n%8 == 0 {
+		b.buf = append(b.buf, p...)
+		b.n += int64(len(p)) * 8
+		return len(p), nil
+	}
+	for _, c := range p {
+		b.WriteBits(uint64(c), 8)
+	}
+	return len(p), nil
+}
+
+// WriteBits appends the n lowest bits of r to the buffer.
+// Bits of r in positions higher than n are ignored.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+func (b *BitBuffer) WriteBits(r uint64, n uint8) (err error) {
+	if n > 64 {
+		return wrapError("WriteBits", int64(n), b.n, ErrInvalidBitCount)
+	}
+	for need := (b.n + int64(n) + 7) / 8; int64(len(b.buf)) < need; {
+		b.buf = append(b.buf, 0)
+	}
+	b.setBits(b.n, r&(1<<n-1), n)
+	b.n += int64(n)
+	return nil
+}
+
+// WriteByte appends 8 bits to the buffer. It never fails.
+//
+// WriteByte implements io.ByteWriter.
+func (b *BitBuffer) WriteByte(c byte) (err error) {
+	return b.WriteBits(uint64(c), 8)
+}
+
+// WriteBool appends one bit to the buffer: 1 if param is true, 0 otherwise. It never fails.
+func (b *BitBuffer) WriteBool(bit bool) (err error) {
+	if bit {
+		return b.WriteBits(1, 1)
+	}
+	return b.WriteBits(0, 1)
+}
+
+// Align appends zero bits to the buffer up to the next byte boundary,
+// and returns their number. It never fails.
+func (b *BitBuffer) Align() (skipped uint8, err error) {
+	skipped = uint8(-b.n & 7)
+	b.n += int64(skipped) // the unused bits of the last byte are zero
+	return
+}
+
+// Close implements io.Closer. It does nothing,
+// the bits of the buffer are always available via Bytes().
+func (b *BitBuffer) Close() (err error) {
+	return nil
+}
+
+// BitPos returns the read cursor: the bit offset of the next read.
+func (b *BitBuffer) BitPos() int64 {
+	return b.off
+}
+
+// SeekBits sets the read cursor to offset, interpreted according to whence:
+// io.SeekStart means relative to the beginning of the buffer, io.SeekCurrent means
+// relative to the read cursor, and io.SeekEnd means relative to the end of the buffer.
+// The new read cursor is returned. Seeking to a negative position is an error.
+func (b *BitBuffer) SeekBits(offset int64, whence int) (pos int64, err error) {
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = b.off + offset
+	case io.SeekEnd:
+		pos = b.n + offset
+	default:
+		return b.off, wrapError("SeekBits", 0, b.off, ErrInvalidParameter)
+	}
+	if pos < 0 {
+		return b.off, wrapError("SeekBits", 0, b.off, ErrInvalidParameter)
+	}
+	b.off = pos
+	return pos, nil
+}
+
+// Read reads up to len(p) bytes (8 * len(p) bits) from the read cursor.
+//
+// Read implements io.Reader. If there are fewer than 8 bits unread (but not zero),
+// io.ErrUnexpectedEOF is returned and no bits are consumed.
+func (b *BitBuffer) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	avail := b.n - b.off
+	if avail < 8 {
+		if avail <= 0 {
+			return 0, io.EOF
+		}
+		return 0, wrapError("Read", int64(len(p))*8, b.off, io.ErrUnexpectedEOF)
+	}
+
+	if int64(len(p)) > avail/8 {
+		p = p[:avail/8]
+	}
+	if b.off%8 == 0 {
+		n = copy(p, b.buf[b.off/8:])
+	} else {
+		for ; n < len(p); n++ {
+			p[n] = byte(b.getBits(b.off+int64(n)*8, 8))
+		}
+	}
+	b.off += int64(n) * 8
+	return n, nil
+}
+
+// ReadBits reads n bits from the read cursor and returns them as the lowest n bits of u.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+//
+// If no bits are available, io.EOF is returned. If fewer than n bits are available,
+// they are consumed and returned in a *PartialReadError (wrapped in a *BitError).
+func (b *BitBuffer) ReadBits(n uint8) (u uint64, err error) {
+	if n > 64 {
+		return 0, wrapError("ReadBits", int64(n), b.off, ErrInvalidBitCount)
+	}
+	if avail := b.n - b.off; avail < int64(n) {
+		if avail <= 0 {
+			return 0, io.EOF
+		}
+		off := b.off
+		b.off = b.n
+		pe := &PartialReadError{Bits: uint8(avail), Value: b.getBits(off, uint8(avail)), Err: io.ErrUnexpectedEOF}
+		return 0, wrapError("ReadBits", int64(n), off, pe)
+	}
+	u = b.getBits(b.off, n)
+	b.off += int64(n)
+	return
+}
+
+// ReadByte reads the next 8 bits from the read cursor and returns them as a byte.
+//
+// ReadByte implements io.ByteReader. If there are fewer than 8 bits available
+// (but not zero), io.ErrUnexpectedEOF is returned and no bits are consumed.
+func (b *BitBuffer) ReadByte() (c byte, err error) {
+	if avail := b.n - b.off; avail < 8 {
+		if avail <= 0 {
+			return 0, io.EOF
+		}
+		return 0, wrapError("ReadByte", 8, b.off, io.ErrUnexpectedEOF)
+	}
+	c = byte(b.getBits(b.off, 8))
+	b.off += 8
+	return
+}
+
+// ReadBool reads the next bit from the read cursor, and returns true if it is 1.
+func (b *BitBuffer) ReadBool() (bit bool, err error) {
+	if b.off >= b.n {
+		return false, io.EOF
+	}
+	bit = b.getBits(b.off, 1) == 1
+	b.off++
+	return
+}
+
+// AlignRead aligns the read cursor to a byte boundary (but not beyond the end of the buffer),
+// so next read will read/use data from the next byte.
+// Returns the number of unread / skipped bits.
+func (b *BitBuffer) AlignRead() (skipped uint8) {
+	skip := -b.off & 7
+	if avail := b.n - b.off; skip > avail {
+		skip = avail
+	}
+	if skip > 0 {
+		skipped = uint8(skip)
+		b.off += skip
+	}
+	return
+}
+
+// BitReader returns a BitReader view of the buffer reading from the read cursor.
+// Its Align() method is AlignRead() of the buffer.
+func (b *BitBuffer) BitReader() BitReader {
+	return bitBufferReader{b}
+}
+
+// bitBufferReader is the BitReader view of a BitBuffer.
+type bitBufferReader struct {
+	*BitBuffer
+}
+
+// Align aligns the read cursor to a byte boundary.
+func (r bitBufferReader) Align() (skipped uint8) {
+	return r.AlignRead()
+}
+
+// checkTry panics if TryPanic is set and a TryError occurred.
+func (b *BitBuffer) checkTry() {
+	if b.TryPanic && b.TryError != nil {
+		panic(tryPanic{b.TryError})
+	}
+}
+
+// TryRead tries to read up to len(p) bytes (8 * len(p) bits) from the read cursor.
+//
+// If there was a previous TryError, it does nothing. Else it calls Read(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryRead(p []byte) (n int) {
+	if b.TryError == nil {
+		n, b.TryError = b.Read(p)
+		b.checkTry()
+	}
+	return
+}
+
+// TryReadBits tries to read n bits from the read cursor.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBits(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryReadBits(n uint8) (u uint64) {
+	if b.TryError == nil {
+		u, b.TryError = b.ReadBits(n)
+		b.checkTry()
+	}
+	return
+}
+
+// TryReadByte tries to read the next 8 bits from the read cursor and return them as a byte.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadByte(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryReadByte() (c byte) {
+	if b.TryError == nil {
+		c, b.TryError = b.ReadByte()
+		b.checkTry()
+	}
+	return
+}
+
+// TryReadBool tries to read the next bit from the read cursor, and return true if it is 1.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBool(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryReadBool() (bit bool) {
+	if b.TryError == nil {
+		bit, b.TryError = b.ReadBool()
+		b.checkTry()
+	}
+	return
+}
+
+// TryWrite tries to append len(p) bytes (8 * len(p) bits) to the buffer.
+//
+// If there was a previous TryError, it does nothing. Else it calls Write(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryWrite(p []byte) (n int) {
+	if b.TryError == nil {
+		n, b.TryError = b.Write(p)
+		b.checkTry()
+	}
+	return
+}
+
+// TryWriteBits tries to append the n lowest bits of r to the buffer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBits(),
+// and stores the error in the TryError field.
+func (b *BitBuffer) TryWriteBits(r uint64, n uint8) {
+	if b.TryError == nil {
+		b.TryError = b.WriteBits(r, n)
+		b.checkTry()
+	}
+}
+
+// TryWriteByte tries to append 8 bits to the buffer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteByte(),
+// and stores the error in the TryError field.
+func (b *BitBuffer) TryWriteByte(c byte) {
+	if b.TryError == nil {
+		b.TryError = b.WriteByte(c)
+		b.checkTry()
+	}
+}
+
+// TryWriteBool tries to append one bit to the buffer: 1 if param is true, 0 otherwise.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBool(),
+// and stores the error in the TryError field.
+func (b *BitBuffer) TryWriteBool(bit bool) {
+	if b.TryError == nil {
+		b.TryError = b.WriteBool(bit)
+		b.checkTry()
+	}
+}
+
+// TryAlign tries to append zero bits to the buffer up to the next byte boundary.
+//
+// If there was a previous TryError, it does nothing. Else it calls Align(),
+// returns the data it provides and stores the error in the TryError field.
+func (b *BitBuffer) TryAlign() (skipped uint8) {
+	if b.TryError == nil {
+		skipped, b.TryError = b.Align()
+		b.checkTry()
+	}
+	return
+}
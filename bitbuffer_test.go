@@ -0,0 +1,177 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestBitBuffer(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newBitBuffer := NewWriter, NewBitBuffer
+		if lsb {
+			newWriter, newBitBuffer = NewWriterLSB, NewBitBufferLSB
+		}
+
+		type entry struct {
+			off int64
+			u   uint64
+			n   uint8
+		}
+		var entries []entry
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		bb := newBitBuffer(nil)
+		for i := 0; i < 1000; i++ {
+			u, n := rand.Uint64(), uint8(rand.Intn(65))
+			u &= 1<<n - 1
+			entries = append(entries, entry{bb.Len(), u, n})
+			eq(nil, w.WriteBits(u, n))
+			eq(nil, bb.WriteBits(u, n))
+		}
+		eq(nil, w.Close())
+		eq(true, bytes.Equal(b.Bytes(), bb.Bytes()))
+		eq(int64(len(bb.Bytes())), (bb.Len()+7)/8)
+
+		for _, e := range entries {
+			expEq(e.u)(bb.GetBits(e.off, e.n))
+		}
+		for _, e := range entries {
+			expEq(e.u)(bb.ReadBits(e.n))
+		}
+		_, err := bb.ReadBits(1)
+		eq(io.EOF, err)
+
+		// Same output as Writer through the BitWriter interface
+		b.Reset()
+		eq(nil, writeSample(newWriter(b)))
+		bb = newBitBuffer(nil)
+		eq(nil, writeSample(bb))
+		eq(true, bytes.Equal(b.Bytes(), bb.Bytes()))
+	}
+}
+
+func TestBitBufferPatch(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	bb := NewBitBuffer(nil)
+	eq(nil, bb.WriteBool(true))
+	lenOff := bb.Len()
+	eq(nil, bb.WriteBits(0, 12)) // Length placeholder
+	eq(3, bb.TryWrite([]byte("abc")))
+	eq(nil, bb.SetBits(lenOff, uint64(bb.Len()-lenOff-12), 12))
+	eq(nil, bb.SetBit(0, false))
+	eq(nil, bb.SetBit(bb.Len()-1, true))
+	eq(int64(37), bb.Len())
+
+	r := NewReader(bytes.NewReader(bb.Bytes()))
+	expEq(false)(r.ReadBool())
+	expEq(uint64(24))(r.ReadBits(12))
+	expEq(uint64('a'))(r.ReadBits(8))
+	expEq(uint64('b'))(r.ReadBits(8))
+	expEq(uint64('c' | 1))(r.ReadBits(8))
+
+	expEq(true)(bb.GetBit(36))
+	expEq(false)(bb.GetBit(0))
+	_, err := bb.GetBit(37)
+	eq(BitError{Op: "GetBit", Width: 1, Offset: 37, Err: ErrInvalidParameter}, bitError(err))
+	eq(true, errors.Is(bb.SetBit(-1, true), ErrInvalidParameter))
+	eq(true, errors.Is(bb.SetBits(30, 0, 8), ErrInvalidParameter))
+	_, err = bb.GetBits(0, 65)
+	eq(true, errors.Is(err, ErrInvalidBitCount))
+	eq(true, errors.Is(bb.WriteBits(0, 65), ErrInvalidBitCount))
+
+	// LSB order
+	bb = NewBitBufferLSB([]byte{0x00, 0xff})
+	eq(nil, bb.SetBits(4, 0x5a, 8))
+	eq(true, bytes.Equal([]byte{0xa0, 0xf5}, bb.Bytes()))
+	expEq(uint64(0x5a))(bb.GetBits(4, 8))
+}
+
+func TestBitBufferRead(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	bb := NewBitBuffer([]byte{0x8f, 0x55, 0x01})
+	eq(int64(24), bb.Len())
+	expEq(uint64(0x08))(bb.ReadBits(4))
+	eq(int64(4), bb.BitPos())
+	p := make([]byte, 4)
+	expEq(2)(bb.Read(p))
+	eq(true, bytes.Equal([]byte{0xf5, 0x50}, p[:2]))
+	expEq(uint64(0x01))(bb.ReadBits(4))
+	_, err := bb.Read(p)
+	eq(io.EOF, err)
+
+	expEq(int64(1))(bb.SeekBits(1, io.SeekStart))
+	eq(uint8(7), bb.AlignRead())
+	expEq(byte(0x55))(bb.ReadByte())
+	expEq(int64(20))(bb.SeekBits(-4, io.SeekEnd))
+	_, err = bb.ReadByte()
+	eq(BitError{Op: "ReadByte", Width: 8, Offset: 20, Err: io.ErrUnexpectedEOF}, bitError(err))
+	_, err = bb.Read(p)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	_, err = bb.ReadBits(8)
+	eq(PartialReadError{Bits: 4, Value: 0x1, Err: io.ErrUnexpectedEOF}, partial(err))
+	_, err = bb.ReadBool()
+	eq(io.EOF, err)
+	_, err = bb.SeekBits(-1, io.SeekStart)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	expEq(int64(16))(bb.SeekBits(-8, io.SeekCurrent))
+
+	// Truncate
+	bb.Truncate(13)
+	eq(int64(13), bb.Len())
+	eq(int64(13), bb.BitPos())
+	eq(true, bytes.Equal([]byte{0x8f, 0x50}, bb.Bytes()))
+	eq(uint8(0), bb.AlignRead()) // Not beyond the end
+	eq(nil, bb.WriteBits(0x7, 3))
+	eq(true, bytes.Equal([]byte{0x8f, 0x57}, bb.Bytes()))
+	expEq(uint8(0))(bb.Align())
+	eq(nil, bb.WriteBool(true))
+	expEq(uint8(7))(bb.Align())
+	eq(int64(24), bb.Len())
+	eq(true, bytes.Equal([]byte{0x8f, 0x57, 0x80}, bb.Bytes()))
+	bb.Reset()
+	eq(int64(0), bb.Len())
+	eq(0, len(bb.Bytes()))
+
+	func() {
+		defer func() {
+			eq("bitio: truncation out of range", recover())
+		}()
+		bb.Truncate(1)
+	}()
+
+	// As the source of a Reader and the output of a Writer
+	bb = NewBitBuffer(nil)
+	w := NewWriter(bb)
+	eq(nil, w.WriteBits(0x123, 12))
+	eq(nil, w.Close())
+	r := NewReader(bb)
+	expEq(uint64(0x123))(r.ReadBits(12))
+
+	// BitReader view
+	bb = NewBitBuffer([]byte{0xc1, 0x80})
+	br := bb.BitReader()
+	eq(true, br.TryReadBool())
+	eq(uint8(7), br.Align())
+	eq(byte(0x80), br.TryReadByte())
+	eq(nil, bb.TryError)
+	br.TryReadBits(1)
+	eq(io.EOF, bb.TryError)
+
+	bb = NewBitBuffer([]byte{0xff})
+	bb.TryPanic = true
+	err = Catch(func() {
+		bb.TryReadBits(4)
+		bb.TryWriteBits(0, 70)
+	})
+	eq(true, errors.Is(err, ErrInvalidBitCount))
+}
@@ -0,0 +1,180 @@
+/*
+
+Common types and values used by Reader, Writer and their counting versions.
+
+*/
+
+package bitio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrOverflow is returned if a value can't be represented in the requested encoding,
+	// or if a value read does not fit into the result.
+	ErrOverflow = errors.New("bitio: overflow")
+
+	// ErrInvalidParameter is returned if a parameter of a code is invalid
+	// (e.g. a zero Golomb divisor).
+	ErrInvalidParameter = errors.New("bitio: invalid parameter")
+
+	// ErrInvalidBitCount is returned if more than 64 bits are to be read or written
+	// as an uint64 value (e.g. by ReadBits() or WriteBits()).
+	ErrInvalidBitCount = errors.New("bitio: invalid bit count")
+
+	// ErrNotSeekable is returned by Reader.SeekBits() if the requested position
+	// can't be reached because the source does not implement io.Seeker.
+	ErrNotSeekable = errors.New("bitio: source is not seekable")
+)
+
+// PartialReadError is returned by ReadBits() (wrapped in a *BitError) if fewer bits
+// are available than requested, but not zero. The available bits are consumed (and counted by CountReader).
+type PartialReadError struct {
+	Bits  uint8  // Number of bits read
+	Value uint64 // The bits read, as the lowest Bits bits
+	Err   error  // io.ErrUnexpectedEOF, or the error of the underlying io.Reader
+}
+
+// Error implements the error interface.
+func (e *PartialReadError) Error() string {
+	return fmt.Sprintf("bitio: partial read of %d bits: %v", e.Bits, e.Err)
+}
+
+// Unwrap returns the underlying error, so errors.Is(err, io.ErrUnexpectedEOF) can be used.
+func (e *PartialReadError) Unwrap() error {
+	return e.Err
+}
+
+// BitError is returned by the operations of Reader and Writer (and CountReader
+// and CountWriter), including the reading and writing of codes, if they fail.
+// It records where the failure happened.
+//
+// io.EOF (no more bits at the end of the input) is returned as is, not wrapped.
+type BitError struct {
+	Op     string // The failed operation, e.g. "ReadBits", "WriteBool", "Align"
+	Width  int64  // Number of bits requested by the operation, 0 if not applicable
+	Offset int64  // Absolute bit offset in the stream at which the operation started
+	Err    error  // The underlying error
+}
+
+// Error implements the error interface.
+func (e *BitError) Error() string {
+	if e.Width > 0 {
+		return fmt.Sprintf("bitio: %s(%d) failed at bit %d: %v", e.Op, e.Width, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("bitio: %s failed at bit %d: %v", e.Op, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error, so errors.Is() and errors.As() can be used
+// to inspect it (e.g. errors.Is(err, io.ErrUnexpectedEOF) or a *PartialReadError).
+func (e *BitError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps err into a *BitError, unless it is nil or io.EOF.
+// If err is a *BitError of an inner operation, its underlying error is wrapped instead,
+// so codes built on other operations report themselves.
+func wrapError(op string, width, offset int64, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if e, ok := err.(*BitError); ok {
+		err = e.Err
+	}
+	return &BitError{Op: op, Width: width, Offset: offset, Err: err}
+}
+
+// noEOF returns io.ErrUnexpectedEOF if err is io.EOF, else err.
+// Used if the end of the input is reached after a part of a code has been read.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// maxInt is the max value of the int type.
+const maxInt = int(^uint(0) >> 1)
+
+// BitReader is the bit-level input implemented by Reader and CountReader
+// (and returned by BitBuffer.BitReader()).
+//
+// Functions accepting a BitReader can read from any bit source, including test fakes.
+type BitReader interface {
+	io.Reader
+	io.ByteReader
+
+	// ReadBits reads n bits and returns them as the lowest n bits of u.
+	ReadBits(n uint8) (u uint64, err error)
+
+	// ReadBool reads the next bit, and returns true if it is 1.
+	ReadBool() (b bool, err error)
+
+	// Align aligns the bit stream to a byte boundary.
+	Align() (skipped uint8)
+
+	// Try variants of the above methods.
+	TryRead(p []byte) (n int)
+	TryReadBits(n uint8) (u uint64)
+	TryReadByte() (b byte)
+	TryReadBool() (b bool)
+}
+
+// BitWriter is the bit-level output implemented by Writer, CountWriter and BitBuffer.
+//
+// Functions accepting a BitWriter can write to any bit sink, including test fakes.
+type BitWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.Closer
+
+	// WriteBits writes out the n lowest bits of r.
+	WriteBits(r uint64, n uint8) (err error)
+
+	// WriteBool writes one bit: 1 if param is true, 0 otherwise.
+	WriteBool(b bool) (err error)
+
+	// Align aligns the bit stream to a byte boundary.
+	Align() (skipped uint8, err error)
+
+	// Try variants of the above methods.
+	TryWrite(p []byte) (n int)
+	TryWriteBits(r uint64, n uint8)
+	TryWriteByte(b byte)
+	TryWriteBool(b bool)
+	TryAlign() (skipped uint8)
+}
+
+// Make sure the implementations satisfy the interfaces.
+var (
+	_ BitReader = (*Reader)(nil)
+	_ BitReader = (*CountReader)(nil)
+	_ BitWriter = (*Writer)(nil)
+	_ BitWriter = (*CountWriter)(nil)
+	_ BitWriter = (*BitBuffer)(nil)
+	_ BitReader = bitBufferReader{}
+)
+
+// bitReader is the bit-level input composite codes are read from.
+//
+// Both Reader and CountReader implement it, so codes read
+// through a CountReader are counted.
+type bitReader interface {
+	io.Reader
+	ReadBits(n uint8) (u uint64, err error)
+	ReadBool() (b bool, err error)
+	ReadUnary(stopBit bool, max int) (n int, err error)
+}
+
+// bitWriter is the bit-level output composite codes are written to.
+//
+// Both Writer and CountWriter implement it, so codes written
+// through a CountWriter are counted.
+type bitWriter interface {
+	io.Writer
+	WriteBits(r uint64, n uint8) (err error)
+	WriteBool(b bool) (err error)
+}
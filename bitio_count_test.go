@@ -2,6 +2,7 @@ package bitio
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math/rand"
 	"testing"
@@ -101,6 +102,81 @@ func TestCountReaderTry(t *testing.T) {
 	eq(nil, r.TryError)
 }
 
+func TestCountReaderPeek(t *testing.T) {
+	r := NewCountReader(bytes.NewBuffer([]byte{0x8f, 0x55, 0x01}))
+	eq, expEq := mighty.EqExpEq(t)
+
+	u, avail, err := r.PeekBits(12)
+	eq(uint64(0x8f5), u)
+	eq(uint8(12), avail)
+	eq(nil, err)
+	expEq(true)(r.PeekBool())
+	eq(int64(0), r.BitsCount)
+
+	expEq(uint64(0x08))(r.ReadBits(4))
+	eq(int64(4), r.BitsCount)
+	u, avail, err = r.PeekBits(20)
+	eq(uint64(0xf5501), u)
+	eq(uint8(20), avail)
+	eq(nil, err)
+	eq(int64(4), r.BitsCount)
+	expEq(uint64(0xf5501))(r.ReadBits(20))
+	eq(int64(24), r.BitsCount)
+}
+
+func TestCountReaderSkip(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	for _, src := range []io.Reader{bytes.NewBuffer(data), bytes.NewReader(data)} {
+		r := NewCountReader(src)
+		eq, expEq := mighty.EqExpEq(t)
+
+		eq(nil, r.SkipBits(3))
+		eq(int64(3), r.BitsCount)
+		eq(nil, r.SkipBits(8*100+5))
+		eq(int64(808), r.BitsCount)
+		expEq(byte(101))(r.ReadByte())
+		eq(int64(816), r.BitsCount)
+
+		r.TrySkipBits(8 * 800)
+		eq(nil, r.TryError)
+		eq(int64(7216), r.BitsCount)
+		eq(byte(902&0xff), r.TryReadByte())
+	}
+
+	r := NewCountReader(bytes.NewBuffer([]byte{0x01, 0x02}))
+	mighty.Eq(t)(io.EOF, r.SkipBits(17))
+	mighty.Eq(t)(int64(16), r.BitsCount)
+}
+
+func TestCountLSB(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriterLSB(b)
+	eq(nil, w.WriteBits(0x0f, 4))
+	eq(nil, w.WriteBits(0x00, 3))
+	eq(nil, w.WriteBits(0x03, 3))
+	eq(nil, w.WriteBool(true))
+	eq(int64(11), w.BitsCount)
+	expEq(uint8(5))(w.Align())
+	eq(int64(16), w.BitsCount)
+	eq(nil, w.Close())
+	eq(true, bytes.Equal(b.Bytes(), []byte{0x8f, 0x05}))
+
+	r := NewCountReaderLSB(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(0x0f))(r.ReadBits(4))
+	expEq(uint64(0x00))(r.ReadBits(3))
+	expEq(uint64(0x03))(r.ReadBits(3))
+	expEq(true)(r.ReadBool())
+	eq(int64(11), r.BitsCount)
+	eq(uint8(5), r.Align())
+	eq(int64(16), r.BitsCount)
+}
+
 func TestCountWriter(t *testing.T) {
 	for i := 0; i < 2; i++ {
 		// 2 rounds, first use something that implements io.ByteWriter (*bytes.Buffer),
@@ -281,24 +357,27 @@ func TestCountReaderEOF2(t *testing.T) {
 
 	r := NewCountReader(bytes.NewBuffer([]byte{0x01}))
 	_, err = r.ReadBits(17)
-	eq(int64(0), r.BitsCount)
-	eq(io.EOF, err)
+	eq(int64(8), r.BitsCount)
+	eq(PartialReadError{Bits: 8, Value: 0x01, Err: io.ErrUnexpectedEOF}, partial(err))
 
 	// Byte spreading byte boundary (readUnalignedByte)
 	r = NewCountReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
 	expEq(true)(r.ReadBool())
 	eq(int64(1), r.BitsCount)
 	expEq(byte(0x82))(r.ReadByte())
-	// readUnalignedByte resulting in EOF
+	// readUnalignedByte resulting in unexpected EOF, bits are not consumed
 	_, err = r.ReadByte()
-	eq(io.EOF, err)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
 	eq(int64(9), r.BitsCount)
+	_, err = r.ReadBits(8)
+	eq(PartialReadError{Bits: 7, Value: 0x01, Err: io.ErrUnexpectedEOF}, partial(err))
+	eq(int64(16), r.BitsCount)
 
 	r = NewCountReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
 	expEq(true)(r.ReadBool())
 	got, err := r.Read(make([]byte, 2))
 	eq(1, got)
-	eq(io.EOF, err)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
 	eq(int64(9), r.BitsCount)
 }
 
@@ -307,8 +386,8 @@ func TestCountReaderTryEOF2(t *testing.T) {
 
 	r := NewCountReader(bytes.NewBuffer([]byte{0x01}))
 	_ = r.TryReadBits(17)
-	eq(io.EOF, r.TryError)
-	eq(int64(0), r.BitsCount)
+	eq(PartialReadError{Bits: 8, Value: 0x01, Err: io.ErrUnexpectedEOF}, partial(r.TryError))
+	eq(int64(8), r.BitsCount)
 
 	// Byte spreading byte boundary (readUnalignedByte)
 	r = NewCountReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
@@ -318,9 +397,9 @@ func TestCountReaderTryEOF2(t *testing.T) {
 	eq(byte(0x82), r.TryReadByte())
 	eq(nil, r.TryError)
 	eq(int64(9), r.BitsCount)
-	// readUnalignedByte resulting in EOF
+	// readUnalignedByte resulting in unexpected EOF
 	_ = r.TryReadByte()
-	eq(io.EOF, r.TryError)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
 	eq(int64(9), r.BitsCount)
 
 	r = NewCountReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
@@ -328,40 +407,49 @@ func TestCountReaderTryEOF2(t *testing.T) {
 	eq(int64(1), r.BitsCount)
 	got := r.TryRead(make([]byte, 2))
 	eq(1, got)
-	eq(io.EOF, r.TryError)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
 	eq(int64(9), r.BitsCount)
 }
 
 func TestCountWriterError(t *testing.T) {
 	eq, neq := mighty.EqNeq(t)
+	expEq := mighty.ExpEq(t)
 
 	w := NewCountWriter(&errWriter{1})
 	eq(nil, w.WriteBool(true))
 	eq(int64(1), w.BitsCount)
-	got, err := w.Write([]byte{0x01, 0x02})
-	eq(1, got)
-	neq(nil, err)
+	expEq(2)(w.Write([]byte{0x01, 0x02}))
+	eq(int64(17), w.BitsCount)
 	neq(nil, w.Close())
-	eq(int64(9), w.BitsCount)
+	eq(int64(24), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{0})
-	neq(nil, w.WriteBits(0x00, 9))
-	eq(int64(0), w.BitsCount)
+	for i := 0; i < writerBufSize/8; i++ {
+		eq(nil, w.WriteBits(0x00, 64))
+	}
+	eq(int64(writerBufSize*8), w.BitsCount)
+	neq(nil, w.WriteBits(0x00, 64))
+	eq(int64(writerBufSize*8), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{1})
-	neq(nil, w.WriteBits(0x00, 17))
-	eq(int64(0), w.BitsCount)
-
-	w = NewCountWriter(&errWriter{})
 	eq(nil, w.WriteBits(0x00, 7))
-	neq(nil, w.WriteBool(false))
-	eq(int64(7), w.BitsCount)
+	for i := 0; i < writerBufSize; i++ {
+		eq(nil, w.WriteByte(0x00))
+	}
+	neq(nil, w.WriteBits(0x00, 57))
+	eq(int64(7+writerBufSize*8), w.BitsCount)
+
+	w = NewCountWriter(&errWriter{1})
+	got, err := w.Write(make([]byte, writerBufSize+1))
+	eq(1, got)
+	neq(nil, err)
+	eq(int64(8), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{})
 	eq(nil, w.WriteBool(true))
 	_, err = w.Align()
 	neq(nil, err)
-	eq(int64(1), w.BitsCount)
+	eq(int64(8), w.BitsCount)
 }
 
 func TestCountWriterTryError(t *testing.T) {
@@ -371,29 +459,33 @@ func TestCountWriterTryError(t *testing.T) {
 	w.TryWriteBool(true)
 	eq(nil, w.TryError)
 	eq(int64(1), w.BitsCount)
-	got := w.TryWrite([]byte{0x01, 0x02})
-	eq(1, got)
+	eq(2, w.TryWrite([]byte{0x01, 0x02}))
+	eq(nil, w.TryError)
+	eq(int64(17), w.BitsCount)
+	w.TryAlign()
 	neq(nil, w.TryError)
-	neq(nil, w.Close())
-	eq(int64(9), w.BitsCount)
+	eq(int64(24), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{0})
-	w.TryWriteBits(0x00, 9)
+	for i := 0; i < writerBufSize/8; i++ {
+		w.TryWriteBits(0x00, 64)
+	}
+	eq(nil, w.TryError)
+	w.TryWriteBitsUnsafe(0x00, 64)
 	neq(nil, w.TryError)
-	eq(int64(0), w.BitsCount)
+	eq(int64(writerBufSize*8), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{1})
-	w.TryWriteBits(0x00, 17)
-	neq(nil, w.TryError)
-	eq(int64(0), w.BitsCount)
-
-	w = NewCountWriter(&errWriter{})
 	w.TryWriteBits(0x00, 7)
-	eq(nil, w.TryError)
-	eq(int64(7), w.BitsCount)
+	for i := 0; i < writerBufSize; i++ {
+		w.TryWriteByte(0x00)
+	}
 	w.TryWriteBool(false)
+	eq(nil, w.TryError)
+	eq(int64(8+writerBufSize*8), w.BitsCount)
+	w.TryWriteBits(0x00, 56)
 	neq(nil, w.TryError)
-	eq(int64(7), w.BitsCount)
+	eq(int64(8+writerBufSize*8), w.BitsCount)
 
 	w = NewCountWriter(&errWriter{})
 	w.TryWriteBool(true)
@@ -401,7 +493,36 @@ func TestCountWriterTryError(t *testing.T) {
 	eq(int64(1), w.BitsCount)
 	_ = w.TryAlign()
 	neq(nil, w.TryError)
-	eq(int64(1), w.BitsCount)
+	eq(int64(8), w.BitsCount)
+}
+
+func TestCountInvalidBitCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteBits(0x1, 4))
+	eq(true, errors.Is(w.WriteBits(1, 65), ErrInvalidBitCount))
+	eq(true, errors.Is(w.WriteBitsUnsafe(1, 65), ErrInvalidBitCount))
+	w.TryWriteBits(1, 65)
+	eq(true, errors.Is(w.TryError, ErrInvalidBitCount))
+	w.TryError = nil
+	w.TryWriteBitsUnsafe(1, 65)
+	eq(true, errors.Is(w.TryError, ErrInvalidBitCount))
+	eq(int64(4), w.BitsCount)
+	eq(nil, w.WriteBits(0x2, 4))
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	_, err := r.ReadBits(65)
+	eq(true, errors.Is(err, ErrInvalidBitCount))
+	_, _, err = r.PeekBits(65)
+	eq(true, errors.Is(err, ErrInvalidBitCount))
+	r.TryReadBits(65)
+	eq(true, errors.Is(r.TryError, ErrInvalidBitCount))
+	eq(int64(0), r.BitsCount)
+	expEq(uint64(0x12))(r.ReadBits(8))
+	eq(int64(8), r.BitsCount)
 }
 
 func TestCountedChain(t *testing.T) {
@@ -3,9 +3,12 @@ package bitio
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/icza/mighty"
@@ -73,8 +76,344 @@ func TestReaderTry(t *testing.T) {
 	eq(nil, r.TryError)
 }
 
-// testWriter that does not implement io.ByteWriter so we can test the
-// behaviour of Writer when it creates an internal bufio.Writer.
+func TestReaderPeek(t *testing.T) {
+	data := []byte{0x8f, 0x55, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	r := NewReader(bytes.NewBuffer(data))
+	eq, expEq := mighty.EqExpEq(t)
+
+	u, avail, err := r.PeekBits(4)
+	eq(uint64(0x08), u)
+	eq(uint8(4), avail)
+	eq(nil, err)
+	expEq(true)(r.PeekBool())
+	expEq(uint64(0x08))(r.ReadBits(4))
+
+	u, avail, err = r.PeekBits(8)
+	eq(uint64(0xf5), u)
+	eq(uint8(8), avail)
+	eq(nil, err)
+	expEq(uint64(0x07))(r.ReadBits(3))
+
+	// Across many byte boundaries
+	u, avail, err = r.PeekBits(64)
+	eq(uint64(0xaa80810182028303), u)
+	eq(uint8(64), avail)
+	eq(nil, err)
+	expEq(uint64(0x155))(r.ReadBits(9))
+	u, avail, err = r.PeekBits(32)
+	eq(uint64(0x01020304), u)
+	eq(uint8(32), avail)
+	eq(nil, err)
+	expEq(uint64(0x01))(r.ReadBits(8))
+
+	// Peeked bytes are served by Read too
+	s := make([]byte, 2)
+	expEq(2)(r.Read(s))
+	eq(true, bytes.Equal(s, []byte{0x02, 0x03}))
+
+	// Near EOF
+	u, avail, err = r.PeekBits(64)
+	eq(uint64(0x0405060708)<<24, u)
+	eq(uint8(40), avail)
+	eq(io.EOF, err)
+	expEq(uint64(0x0405060708))(r.ReadBits(40))
+
+	u, avail, err = r.PeekBits(1)
+	eq(uint64(0), u)
+	eq(uint8(0), avail)
+	eq(io.EOF, err)
+	_, err = r.PeekBool()
+	eq(io.EOF, err)
+}
+
+func TestReaderTryPeek(t *testing.T) {
+	r := NewReader(bytes.NewBuffer([]byte{0x8f, 0x55}))
+	eq := mighty.Eq(t)
+
+	u, avail := r.TryPeekBits(12)
+	eq(uint64(0x8f5), u)
+	eq(uint8(12), avail)
+	eq(true, r.TryPeekBool())
+	eq(uint64(0x8f55), r.TryReadBits(16))
+	eq(nil, r.TryError)
+
+	eq(false, r.TryPeekBool())
+	eq(io.EOF, r.TryError)
+}
+
+func TestReaderLSB(t *testing.T) {
+	r := NewReaderLSB(bytes.NewBuffer([]byte{0x8f, 0x55, 0xc1, 0x80, 0x01, 0x02, 0x03, 0xaa}))
+	eq, expEq := mighty.EqExpEq(t)
+
+	expEq(uint64(0x0f))(r.ReadBits(4))
+	expEq(uint64(0x00))(r.ReadBits(3))
+	expEq(uint64(0x03))(r.ReadBits(3))
+	expEq(uint64(0x15))(r.ReadBits(6))
+
+	expEq(true)(r.ReadBool())
+	expEq(false)(r.ReadBool())
+	u, avail, err := r.PeekBits(12)
+	eq(uint64(0x030), u)
+	eq(uint8(12), avail)
+	eq(nil, err)
+	expEq(uint64(0x30))(r.ReadBits(6))
+	expEq(byte(0x80))(r.ReadByte())
+	eq(uint8(0), r.Align())
+
+	s := make([]byte, 2)
+	expEq(2)(r.Read(s))
+	eq(true, bytes.Equal(s, []byte{0x01, 0x02}))
+
+	expEq(uint64(0x03))(r.ReadBits(4))
+	expEq(uint64(0x00))(r.ReadBits(4))
+	expEq(uint64(0x0a))(r.ReadBits(4))
+	u, avail, err = r.PeekBits(8)
+	eq(uint64(0x0a), u)
+	eq(uint8(4), avail)
+	eq(io.EOF, err)
+	expEq(uint64(0x0a))(r.ReadBits(4))
+}
+
+func TestWriterLSB(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		buf := &bytes.Buffer{}
+		var b io.Writer = buf
+		if i > 0 {
+			b = &testWriter{b: buf}
+		}
+		w := NewWriterLSB(b)
+		eq, expEq := mighty.EqExpEq(t)
+
+		eq(nil, w.WriteBits(0x0f, 4))
+		eq(nil, w.WriteBits(0x00, 3))
+		eq(nil, w.WriteBits(0x03, 3))
+		eq(nil, w.WriteBits(0x15, 6))
+
+		eq(nil, w.WriteBool(true))
+		eq(nil, w.WriteBool(false))
+		eq(nil, w.WriteBits(0x30, 6))
+		eq(nil, w.WriteByte(0x00))
+		expEq(2)(w.Write([]byte{0xaa, 0x55}))
+		eq(nil, w.WriteBits(0x07, 3))
+		expEq(uint8(5))(w.Align())
+		w.TryWriteBits(0xabc, 12)
+		w.TryWriteBool(true)
+		eq(nil, w.TryError)
+		eq(nil, w.Close())
+
+		eq(true, bytes.Equal(buf.Bytes(), []byte{0x8f, 0x55, 0xc1, 0x00, 0xaa, 0x55, 0x07, 0xbc, 0x1a}))
+	}
+}
+
+func TestChainLSB(t *testing.T) {
+	eq, expEq := mighty.Eq(t), mighty.ExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriterLSB(b)
+
+	rand.Seed(time.Now().UnixNano())
+
+	expected := make([]uint64, 100000)
+	bits := make([]byte, len(expected))
+
+	// Writing (generating)
+	for i := range expected {
+		expected[i] = uint64(rand.Int63())
+		bits[i] = byte(1 + rand.Int31n(64))
+		expected[i] &= uint64(1)<<bits[i] - 1
+		switch {
+		case bits[i] == 1:
+			w.WriteBool(expected[i] == 1)
+		case bits[i] == 8:
+			w.WriteByte(byte(expected[i]))
+		default:
+			w.WriteBits(expected[i], bits[i])
+		}
+	}
+
+	eq(nil, w.Close())
+
+	r := NewReaderLSB(bytes.NewBuffer(b.Bytes()))
+
+	// Reading (verifying)
+	for i, v := range expected {
+		switch {
+		case bits[i] == 1:
+			expEq(v == 1)(r.ReadBool())
+		case bits[i] == 8:
+			expEq(byte(v))(r.ReadByte())
+		case bits[i]%3 == 0:
+			u, avail, err := r.PeekBits(bits[i])
+			eq(nil, err)
+			eq(bits[i], avail)
+			eq(v, u)
+			expEq(v)(r.ReadBits(bits[i]))
+		default:
+			expEq(v)(r.ReadBits(bits[i]))
+		}
+	}
+}
+
+// readSeeker implements io.Reader and io.Seeker only.
+type readSeeker struct {
+	r *bytes.Reader
+}
+
+func (rs *readSeeker) Read(p []byte) (n int, err error) {
+	return rs.r.Read(p)
+}
+
+func (rs *readSeeker) Seek(offset int64, whence int) (int64, error) {
+	return rs.r.Seek(offset, whence)
+}
+
+func TestReaderSkip(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	sources := []func() io.Reader{
+		func() io.Reader { return bytes.NewBuffer(data) },              // not an io.Seeker
+		func() io.Reader { return bytes.NewReader(data) },              // io.ByteReader and io.Seeker
+		func() io.Reader { return &readSeeker{bytes.NewReader(data)} }, // io.Seeker only
+	}
+
+	for _, src := range sources {
+		r := NewReader(src())
+		eq, expEq := mighty.EqExpEq(t)
+
+		eq(nil, r.SkipBits(0))
+		eq(nil, r.SkipBits(3))
+		expEq(uint64(0))(r.ReadBits(5))
+		eq(nil, r.SkipBits(8*100+4))
+		expEq(uint64(101 & 0x0f))(r.ReadBits(4))
+		expEq(byte(102))(r.ReadByte())
+
+		// Skip bytes read ahead by PeekBits()
+		_, _, err := r.PeekBits(64)
+		eq(nil, err)
+		eq(nil, r.SkipBits(3*8+1))
+		expEq(uint64(106 >> 6 & 0x01))(r.ReadBits(1))
+		eq(nil, r.SkipBits(6))
+
+		// Long skip
+		eq(nil, r.SkipBits(8*8000))
+		expEq(byte(8107 & 0xff))(r.ReadByte())
+
+		r.TrySkipBits(1)
+		eq(uint64(8108&0x7f), r.TryReadBits(7))
+		eq(nil, r.TryError)
+	}
+
+	// Skipping past the end of a non-seekable source
+	r := NewReader(bytes.NewBuffer([]byte{0x01, 0x02}))
+	mighty.Eq(t)(io.EOF, r.SkipBits(17))
+}
+
+func TestReaderSeekBits(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	data := make([]byte, 10003)
+	rand.Read(data)
+
+	for _, lsb := range []bool{false, true} {
+		newReader := NewCountReader
+		if lsb {
+			newReader = NewCountReaderLSB
+		}
+		// bitsAt returns n bits at bit position pos (relative to data[3:])
+		bitsAt := func(pos int64, n uint8) uint64 {
+			r := newReader(bytes.NewReader(data[3:]))
+			r.SkipBits(pos)
+			u, _ := r.ReadBits(n)
+			return u
+		}
+
+		src := bytes.NewReader(data)
+		src.Seek(3, io.SeekStart) // Positions are relative to the source position at creation
+		r := newReader(src)
+		expEq(bitsAt(0, 13))(r.ReadBits(13))
+		eq(int64(13), r.BitPos())
+
+		expEq(int64(8000*8 + 5))(r.SeekBits(8000*8+5, io.SeekStart))
+		expEq(bitsAt(8000*8+5, 20))(r.ReadBits(20))
+		expEq(int64(8000*8 - 75))(r.SeekBits(-100, io.SeekCurrent)) // Within the buffer
+		expEq(bitsAt(8000*8-75, 64))(r.ReadBits(64))
+		expEq(int64(10000*8 - 3))(r.SeekBits(-3, io.SeekEnd))
+		expEq(bitsAt(10000*8-3, 3))(r.ReadBits(3))
+		_, err := r.ReadBool()
+		eq(io.EOF, err)
+		eq(int64(13+20+64+3), r.BitsCount)
+
+		eq(int64(7), r.TrySeekBits(7, io.SeekStart))
+		eq(bitsAt(7, 33), r.TryReadBits(33))
+		eq(nil, r.TryError)
+		eq(int64(40), r.BitPos())
+
+		pos, err := r.SeekBits(-1, io.SeekStart)
+		eq(int64(40), pos)
+		eq(BitError{Op: "SeekBits", Offset: 40, Err: ErrInvalidParameter}, bitError(err))
+		_, err = r.SeekBits(0, 3)
+		eq(true, errors.Is(err, ErrInvalidParameter))
+		expEq(int64(10000 * 8))(r.SeekBits(0, io.SeekEnd))
+		_, err = r.SeekBits(1, io.SeekCurrent) // Past the end, not at a byte boundary
+		eq(io.EOF, err)
+
+		// A failed seek relative to the end leaves the source in place
+		r = newReader(bytes.NewReader(data[3:]))
+		expEq(bitsAt(0, 8))(r.ReadBits(8))
+		_, err = r.SeekBits(-1000000, io.SeekEnd)
+		eq(true, errors.Is(err, ErrInvalidParameter))
+		eq(int64(8), r.BitPos())
+		expEq(int64(40000))(r.SeekBits(40000, io.SeekStart))
+		expEq(bitsAt(40000, 8))(r.ReadBits(8))
+		expEq(int64(10000*8 - 4))(r.SeekBits(-4, io.SeekEnd))
+		expEq(bitsAt(10000*8-4, 4))(r.ReadBits(4))
+
+		// Source not implementing io.Seeker
+		r = newReader(bytes.NewBuffer(data[3:]))
+		expEq(bitsAt(0, 16))(r.ReadBits(16))
+		expEq(int64(3))(r.SeekBits(3, io.SeekStart)) // Within the buffer
+		expEq(bitsAt(3, 9))(r.ReadBits(9))
+		expEq(int64(9000*8 + 1))(r.SeekBits(9000*8+1, io.SeekStart)) // Forward
+		expEq(bitsAt(9000*8+1, 9))(r.ReadBits(9))
+		_, err = r.SeekBits(0, io.SeekStart)
+		eq(true, errors.Is(err, ErrNotSeekable))
+		_, err = r.SeekBits(0, io.SeekEnd)
+		eq(true, errors.Is(err, ErrNotSeekable))
+		expEq(bitsAt(9000*8+10, 9))(r.ReadBits(9))
+
+		// Bytes skipped or read around the buffer don't leave stale data behind
+		for _, seekable := range []bool{true, false} {
+			r = newReader(bytes.NewBuffer(data[3:]))
+			if seekable {
+				r = newReader(bytes.NewReader(data[3:]))
+			}
+			expEq(data[3])(r.ReadByte())
+			eq(nil, r.SkipBits(8000*8))
+			pos, err = r.SeekBits(-800, io.SeekCurrent)
+			if seekable {
+				eq(int64(7901*8), pos)
+				expEq(data[3+7901])(r.ReadByte())
+			} else {
+				eq(true, errors.Is(err, ErrNotSeekable))
+			}
+		}
+
+		r = newReader(bytes.NewReader(data[3:]))
+		expEq(data[3])(r.ReadByte())
+		p := make([]byte, 5000)
+		expEq(readerBufSize - 1)(r.Read(p))
+		expEq(5000)(r.Read(p)) // Directly from the source
+		expEq(int64(readerBufSize+4900) * 8)(r.SeekBits(-800, io.SeekCurrent))
+		expEq(data[3+readerBufSize+4900])(r.ReadByte())
+	}
+}
+
+// testWriter that does not implement io.ByteWriter so we can test
+// Writer with a plain io.Writer output.
 type testWriter struct {
 	b *bytes.Buffer
 }
@@ -244,13 +583,18 @@ func TestReaderEOF2(t *testing.T) {
 
 	r := NewReader(bytes.NewBuffer([]byte{0x01}))
 	_, err = r.ReadBits(17)
+	eq(PartialReadError{Bits: 8, Value: 0x01, Err: io.ErrUnexpectedEOF}, partial(err))
+	_, err = r.ReadBits(1)
 	eq(io.EOF, err)
 
 	// Byte spreading byte boundary (readUnalignedByte)
 	r = NewReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
 	expEq(true)(r.ReadBool())
 	expEq(byte(0x82))(r.ReadByte())
-	// readUnalignedByte resulting in EOF
+	// readUnalignedByte resulting in unexpected EOF, bits are not consumed
+	_, err = r.ReadByte()
+	eq(BitError{Op: "ReadByte", Width: 8, Offset: 9, Err: io.ErrUnexpectedEOF}, bitError(err))
+	expEq(uint64(0x01))(r.ReadBits(7))
 	_, err = r.ReadByte()
 	eq(io.EOF, err)
 
@@ -258,7 +602,52 @@ func TestReaderEOF2(t *testing.T) {
 	expEq(true)(r.ReadBool())
 	got, err := r.Read(make([]byte, 2))
 	eq(1, got)
+	eq(BitError{Op: "Read", Width: 16, Offset: 1, Err: io.ErrUnexpectedEOF}, bitError(err))
+}
+
+// partial returns the *PartialReadError in err's chain dereferenced,
+// or the zero value if there is none.
+func partial(err error) PartialReadError {
+	var pe *PartialReadError
+	if errors.As(err, &pe) {
+		return *pe
+	}
+	return PartialReadError{}
+}
+
+// bitError returns the *BitError in err's chain dereferenced,
+// or the zero value if there is none.
+func bitError(err error) BitError {
+	var be *BitError
+	if errors.As(err, &be) {
+		return *be
+	}
+	return BitError{}
+}
+
+func TestReaderPartialRead(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	r := NewReader(bytes.NewBuffer([]byte{0xab, 0xcd, 0xef}))
+	expEq(uint64(0xa))(r.ReadBits(4))
+	_, err := r.ReadBits(64)
+	eq(PartialReadError{Bits: 20, Value: 0xbcdef, Err: io.ErrUnexpectedEOF}, partial(err))
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	eq("bitio: ReadBits(64) failed at bit 4: bitio: partial read of 20 bits: unexpected EOF", err.Error())
+	_, err = r.ReadBits(1)
 	eq(io.EOF, err)
+
+	r = NewReaderLSB(bytes.NewBuffer([]byte{0xab, 0xcd, 0xef}))
+	expEq(uint64(0xb))(r.ReadBits(4))
+	_, err = r.ReadBits(21)
+	eq(PartialReadError{Bits: 20, Value: 0xefcda, Err: io.ErrUnexpectedEOF}, partial(err))
+
+	// Other errors are kept
+	r = NewReader(io.MultiReader(bytes.NewBuffer([]byte{0xff}), iotest.TimeoutReader(bytes.NewBuffer([]byte{0, 0}))))
+	_, err = r.ReadBits(64)
+	pe := partial(err)
+	eq(uint8(24), pe.Bits)
+	eq(iotest.ErrTimeout, pe.Err)
 }
 
 func TestReaderTryEOF2(t *testing.T) {
@@ -266,7 +655,7 @@ func TestReaderTryEOF2(t *testing.T) {
 
 	r := NewReader(bytes.NewBuffer([]byte{0x01}))
 	_ = r.TryReadBits(17)
-	eq(io.EOF, r.TryError)
+	eq(PartialReadError{Bits: 8, Value: 0x01, Err: io.ErrUnexpectedEOF}, partial(r.TryError))
 
 	// Byte spreading byte boundary (readUnalignedByte)
 	r = NewReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
@@ -274,15 +663,15 @@ func TestReaderTryEOF2(t *testing.T) {
 	eq(nil, r.TryError)
 	eq(byte(0x82), r.TryReadByte())
 	eq(nil, r.TryError)
-	// readUnalignedByte resulting in EOF
+	// readUnalignedByte resulting in unexpected EOF
 	_ = r.TryReadByte()
-	eq(io.EOF, r.TryError)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
 
 	r = NewReader(bytes.NewBuffer([]byte{0xc1, 0x01}))
 	eq(true, r.TryReadBool())
 	got := r.TryRead(make([]byte, 2))
 	eq(1, got)
-	eq(io.EOF, r.TryError)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
 }
 
 type nonByteReaderWriter struct {
@@ -318,23 +707,35 @@ func (e *errWriter) Write(p []byte) (n int, err error) {
 
 func TestWriterError(t *testing.T) {
 	eq, neq := mighty.EqNeq(t)
+	expEq := mighty.ExpEq(t)
 
+	// Errors surface when buffered data is flushed
 	w := NewWriter(&errWriter{1})
 	eq(nil, w.WriteBool(true))
-	got, err := w.Write([]byte{0x01, 0x02})
-	eq(1, got)
-	neq(nil, err)
+	expEq(2)(w.Write([]byte{0x01, 0x02}))
 	neq(nil, w.Close())
+	_, err := w.Align()
+	neq(nil, err)
 
+	// Flush when the buffer gets full
 	w = NewWriter(&errWriter{0})
-	neq(nil, w.WriteBits(0x00, 9))
+	for i := 0; i < writerBufSize/8; i++ {
+		eq(nil, w.WriteBits(0x00, 64))
+	}
+	neq(nil, w.WriteBits(0x00, 64))
 
 	w = NewWriter(&errWriter{1})
-	neq(nil, w.WriteBits(0x00, 17))
-
-	w = NewWriter(&errWriter{})
 	eq(nil, w.WriteBits(0x00, 7))
-	neq(nil, w.WriteBool(false))
+	for i := 0; i < writerBufSize; i++ {
+		eq(nil, w.WriteByte(0x00))
+	}
+	neq(nil, w.WriteBits(0x00, 57))
+
+	// Large write goes directly to the output
+	w = NewWriter(&errWriter{1})
+	got, err := w.Write(make([]byte, writerBufSize+1))
+	eq(1, got)
+	neq(nil, err)
 
 	w = NewWriter(&errWriter{})
 	eq(nil, w.WriteBool(true))
@@ -348,23 +749,33 @@ func TestWriterTryError(t *testing.T) {
 	w := NewWriter(&errWriter{1})
 	w.TryWriteBool(true)
 	eq(nil, w.TryError)
-	got := w.TryWrite([]byte{0x01, 0x02})
-	eq(1, got)
+	eq(2, w.TryWrite([]byte{0x01, 0x02}))
+	eq(nil, w.TryError)
+	w.TryAlign()
 	neq(nil, w.TryError)
 	neq(nil, w.Close())
 
 	w = NewWriter(&errWriter{0})
-	w.TryWriteBits(0x00, 9)
+	for i := 0; i < writerBufSize/8; i++ {
+		w.TryWriteBits(0x00, 64)
+	}
+	eq(nil, w.TryError)
+	w.TryWriteBits(0x00, 64)
 	neq(nil, w.TryError)
 
 	w = NewWriter(&errWriter{1})
-	w.TryWriteBits(0x00, 17)
-	neq(nil, w.TryError)
-
-	w = NewWriter(&errWriter{})
 	w.TryWriteBits(0x00, 7)
+	for i := 0; i < writerBufSize; i++ {
+		w.TryWriteByte(0x00)
+	}
 	eq(nil, w.TryError)
 	w.TryWriteBool(false)
+	eq(nil, w.TryError)
+	w.TryWriteBitsUnsafe(0x00, 56)
+	neq(nil, w.TryError)
+
+	w = NewWriter(&errWriter{1})
+	eq(1, w.TryWrite(make([]byte, writerBufSize+1)))
 	neq(nil, w.TryError)
 
 	w = NewWriter(&errWriter{})
@@ -374,6 +785,164 @@ func TestWriterTryError(t *testing.T) {
 	neq(nil, w.TryError)
 }
 
+func TestInvalidBitCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		eq(true, errors.Is(w.WriteBits(1, 65), ErrInvalidBitCount))
+		eq(true, errors.Is(w.WriteBitsUnsafe(1, 65), ErrInvalidBitCount))
+		eq(nil, w.WriteBits(0x12, 8))
+		// Also when acc is partly filled
+		eq(true, errors.Is(w.WriteBits(0, 255), ErrInvalidBitCount))
+		eq(true, errors.Is(w.WriteBitsUnsafe(0, 70), ErrInvalidBitCount))
+		w.TryWriteBits(1, 65)
+		eq(true, errors.Is(w.TryError, ErrInvalidBitCount))
+		w.TryError = nil
+		w.TryWriteBitsUnsafe(1, 65)
+		eq(true, errors.Is(w.TryError, ErrInvalidBitCount))
+		eq(nil, w.WriteBits(0x34, 8))
+		eq(nil, w.Close())
+		eq(true, bytes.Equal(b.Bytes(), []byte{0x12, 0x34}))
+
+		first, rest := uint64(0x1), uint64(0x234)
+		if lsb {
+			first, rest = 0x2, 0x341
+		}
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		u, avail, err := r.PeekBits(65)
+		eq(uint64(0), u)
+		eq(uint8(0), avail)
+		eq(true, errors.Is(err, ErrInvalidBitCount))
+		_, err = r.ReadBits(65)
+		eq(true, errors.Is(err, ErrInvalidBitCount))
+		expEq(first)(r.ReadBits(4))
+		// Also when acc is partly filled
+		_, err = r.ReadBits(255)
+		eq(true, errors.Is(err, ErrInvalidBitCount))
+		r.TryReadBits(70)
+		eq(true, errors.Is(r.TryError, ErrInvalidBitCount))
+		r.TryError = nil
+		r.TryPeekBits(70)
+		eq(true, errors.Is(r.TryError, ErrInvalidBitCount))
+		expEq(rest)(r.ReadBits(12))
+	}
+}
+
+func TestBitError(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	data := make([]byte, readerBufSize+1000) // More than the buffer
+	for _, in := range []func() io.Reader{
+		func() io.Reader { return bytes.NewBuffer(data) },
+		func() io.Reader { return bytes.NewReader(data) }, // Skipping by seeking
+	} {
+		r := NewCountReader(in())
+		eq(nil, r.SkipBits(int64(len(data))*8-4))
+		_, err := r.ReadBits(12)
+		eq("ReadBits", bitError(err).Op)
+		eq(int64(12), bitError(err).Width)
+		eq(int64(len(data))*8-4, bitError(err).Offset)
+		eq(uint8(4), partial(err).Bits)
+		eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+		eq("bitio: ReadBits(12) failed at bit 40764: bitio: partial read of 4 bits: unexpected EOF", err.Error())
+		eq(r.BitsCount, bitError(err).Offset+4)
+
+		// io.EOF is not wrapped
+		_, err = r.ReadBool()
+		eq(io.EOF, err)
+	}
+
+	r := NewReader(bytes.NewBuffer([]byte{0xff, 0x00}))
+	eq(nil, r.SkipBits(3))
+	_, err := r.ReadUnary(false, 2)
+	eq(BitError{Op: "ReadUnary", Offset: 3, Err: ErrOverflow}, bitError(err))
+	eq("bitio: ReadUnary failed at bit 3: bitio: overflow", err.Error())
+	_, _, err = r.PeekBits(70)
+	eq(BitError{Op: "PeekBits", Width: 70, Offset: 6, Err: ErrInvalidBitCount}, bitError(err))
+	_, err = r.ReadBits(12)
+	eq(BitError{Op: "ReadBits", Width: 12, Offset: 6, Err: bitError(err).Err}, bitError(err))
+	eq(PartialReadError{Bits: 10, Value: 0x300, Err: io.ErrUnexpectedEOF}, partial(err))
+
+	// Errors surface when buffered data is flushed
+	w := NewCountWriter(&errWriter{1000})
+	for i := 0; ; i++ {
+		if err = w.WriteBits(0, 64); err != nil {
+			eq(BitError{Op: "WriteBits", Width: 64, Offset: 512 * 64, Err: bitError(err).Err}, bitError(err))
+			eq(int64(512*64), w.BitsCount)
+			break
+		}
+	}
+	eq(nil, w.WriteBool(true))
+	err = w.Close()
+	eq(BitError{Op: "Close", Offset: 512*64 + 1, Err: bitError(err).Err}, bitError(err))
+	eq("bitio: Close failed at bit 32769: Can't write more", err.Error())
+
+	w = NewCountWriter(&errWriter{1})
+	_, err = w.Write(make([]byte, writerBufSize+1))
+	eq(BitError{Op: "Write", Width: (writerBufSize + 1) * 8, Offset: 0, Err: bitError(err).Err}, bitError(err))
+
+	// Codes report themselves with the offset they started at
+	cw := NewCountWriter(&bytes.Buffer{})
+	eq(nil, cw.WriteBits(0, 5))
+	err = cw.WriteEliasGamma(0)
+	eq(BitError{Op: "WriteEliasGamma", Offset: 5, Err: ErrOverflow}, bitError(err))
+	cw.Strict = true
+	err = cw.WriteSigned(8, 4)
+	eq(BitError{Op: "WriteSigned", Width: 4, Offset: 5, Err: ErrOverflow}, bitError(err))
+
+	r = NewReader(bytes.NewBuffer([]byte{0x80, 0x01}))
+	eq(nil, r.SkipBits(1))
+	_, err = r.ReadUE(0)
+	eq(BitError{Op: "ReadUE", Offset: 1, Err: io.ErrUnexpectedEOF}, bitError(err))
+	eq("bitio: ReadUE failed at bit 1: unexpected EOF", err.Error())
+	r = NewReader(bytes.NewBuffer([]byte{0xff}))
+	_, err = r.ReadSigned(12)
+	eq(BitError{Op: "ReadSigned", Width: 12, Offset: 0, Err: bitError(err).Err}, bitError(err))
+	eq(PartialReadError{Bits: 8, Value: 0xff, Err: io.ErrUnexpectedEOF}, partial(err))
+	err = r.ReadBitsInto(make([]byte, 1), -1)
+	eq(BitError{Op: "ReadBitsInto", Width: -1, Offset: 8, Err: ErrInvalidParameter}, bitError(err))
+}
+
+// writeSample writes a sample through the BitWriter interface.
+func writeSample(w BitWriter) error {
+	w.TryWriteBits(0x08, 4)
+	w.TryWriteBool(true)
+	w.TryAlign()
+	w.TryWriteByte(0x55)
+	w.TryWrite([]byte{0x12})
+	if err := w.WriteBits(0x3, 2); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func TestBitReaderWriter(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	for _, w := range []BitWriter{NewWriter(&bytes.Buffer{}), NewCountWriter(&bytes.Buffer{})} {
+		eq(nil, writeSample(w))
+	}
+
+	b := &bytes.Buffer{}
+	eq(nil, writeSample(NewWriter(b)))
+	for _, r := range []BitReader{NewReader(bytes.NewBuffer(b.Bytes())), NewCountReader(bytes.NewBuffer(b.Bytes()))} {
+		expEq(uint64(0x08))(r.ReadBits(4))
+		eq(true, r.TryReadBool())
+		eq(uint8(3), r.Align())
+		eq(byte(0x55), r.TryReadByte())
+		expEq(byte(0x12))(r.ReadByte())
+		expEq(true)(r.ReadBool())
+		eq(uint64(0x40), r.TryReadBits(7))
+	}
+}
+
 func TestChain(t *testing.T) {
 	eq, expEq := mighty.Eq(t), mighty.ExpEq(t)
 
@@ -402,3 +971,131 @@ func TestChain(t *testing.T) {
 		expEq(v)(r.ReadBits(bits[i]))
 	}
 }
+
+func TestReaderSmallReads(t *testing.T) {
+	eq, expEq := mighty.Eq(t), mighty.ExpEq(t)
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+
+		expected := make([]uint64, 10000)
+		bits := make([]byte, len(expected))
+		for i := range expected {
+			expected[i] = rand.Uint64()
+			bits[i] = byte(rand.Int31n(65))
+			expected[i] &= uint64(1)<<bits[i] - 1
+			w.WriteBits(expected[i], bits[i])
+		}
+		eq(nil, w.Close())
+
+		// Source giving 1 byte per Read(), and data along with io.EOF
+		r := newReader(iotest.DataErrReader(iotest.OneByteReader(bytes.NewReader(b.Bytes()))))
+		for i, v := range expected {
+			if i%5 == 0 {
+				u, avail, err := r.PeekBits(bits[i])
+				eq(nil, err)
+				eq(bits[i], avail)
+				eq(v, u)
+			}
+			expEq(v)(r.ReadBits(bits[i]))
+		}
+		r.Align()
+		_, err := r.ReadBits(1)
+		eq(io.EOF, err)
+	}
+}
+
+// legacyReader is the original, byte cache based implementation of Reader.ReadBits(),
+// kept for benchmark comparison.
+type legacyReader struct {
+	in    io.ByteReader
+	cache byte
+	bits  byte
+}
+
+func (r *legacyReader) ReadBits(n uint8) (u uint64, err error) {
+	if n < r.bits {
+		shift := r.bits - n
+		u = uint64(r.cache >> shift)
+		r.cache &= 1<<shift - 1
+		r.bits = shift
+		return
+	}
+
+	if n > r.bits {
+		if r.bits > 0 {
+			u = uint64(r.cache)
+			n -= r.bits
+		}
+		for n >= 8 {
+			b, err2 := r.in.ReadByte()
+			if err2 != nil {
+				return 0, err2
+			}
+			u = u<<8 + uint64(b)
+			n -= 8
+		}
+		if n > 0 {
+			if r.cache, err = r.in.ReadByte(); err != nil {
+				return 0, err
+			}
+			shift := 8 - n
+			u = u<<n + uint64(r.cache>>shift)
+			r.cache &= 1<<shift - 1
+			r.bits = shift
+		} else {
+			r.bits = 0
+		}
+		return u, nil
+	}
+
+	r.bits = 0
+	return uint64(r.cache), nil
+}
+
+func BenchmarkReadBits(b *testing.B) {
+	data := make([]byte, 1<<16)
+	rand.Read(data)
+
+	for _, n := range []uint8{1, 3, 8, 13, 32, 57, 64} {
+		perData := len(data) * 8 / int(n) // number of reads data is enough for
+
+		b.Run(fmt.Sprintf("legacy-%d", n), func(b *testing.B) {
+			var r *legacyReader
+			for i := 0; i < b.N; i++ {
+				if i%perData == 0 {
+					r = &legacyReader{in: bytes.NewReader(data)}
+				}
+				r.ReadBits(n)
+			}
+		})
+
+		b.Run(fmt.Sprintf("new-%d", n), func(b *testing.B) {
+			var r *Reader
+			for i := 0; i < b.N; i++ {
+				if i%perData == 0 {
+					r = NewReader(bytes.NewReader(data))
+				}
+				r.ReadBits(n)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteBits(b *testing.B) {
+	for _, n := range []uint8{1, 3, 8, 13, 32, 57, 64} {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			w := NewWriter(ioutil.Discard)
+			for i := 0; i < b.N; i++ {
+				w.WriteBits(uint64(i), n)
+			}
+			w.Close()
+		})
+	}
+}
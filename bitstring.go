@@ -0,0 +1,315 @@
+/*
+
+Bit strings of arbitrary length.
+
+*/
+
+package bitio
+
+import (
+	"io"
+	"math/big"
+)
+
+// Bit strings are stored in byte slices in the bit order of the Reader / Writer:
+// in highest-bits-first order the first bit is the highest bit of the first byte,
+// in least-significant-bit-first order it is the lowest bit of the first byte.
+// Bits of a last partial byte are stored accordingly, its remaining bits are zero.
+// So reading a byte aligned bit string results in the same bytes as the input.
+//
+// Big integers of n bits are read and written as if ReadBits() / WriteBits()
+// supported n bits: in highest-bits-first order the first bit is the highest bit
+// of the value, in least-significant-bit-first order it is the lowest.
+
+// ReadBitsInto reads nbits bits into dst.
+//
+// ErrInvalidParameter is returned if nbits is negative or dst is too small to hold nbits bits.
+// If there are fewer than nbits bits available, io.ErrUnexpectedEOF may be returned.
+func (r *Reader) ReadBitsInto(dst []byte, nbits int) (err error) {
+	off := r.bitPos()
+	if err = readBitsInto(r, r.lsb, dst, nbits); err != nil {
+		err = wrapError("ReadBitsInto", int64(nbits), off, err)
+	}
+	return
+}
+
+// ReadBigInt reads nbits bits and returns them as a non-negative big integer.
+//
+// ErrInvalidParameter is returned if nbits is negative.
+func (r *Reader) ReadBigInt(nbits int) (x *big.Int, err error) {
+	off := r.bitPos()
+	if x, err = readBigInt(r, r.lsb, nbits); err != nil {
+		err = wrapError("ReadBigInt", int64(nbits), off, err)
+	}
+	return
+}
+
+// TryReadBitsInto tries to read nbits bits into dst.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBitsInto(),
+// and stores the error in the TryError field.
+func (r *Reader) TryReadBitsInto(dst []byte, nbits int) {
+	if r.TryError == nil {
+		r.TryError = r.ReadBitsInto(dst, nbits)
+		r.checkTry()
+	}
+}
+
+// TryReadBigInt tries to read nbits bits as a non-negative big integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBigInt(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadBigInt(nbits int) (x *big.Int) {
+	if r.TryError == nil {
+		x, r.TryError = r.ReadBigInt(nbits)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadBitsInto reads nbits bits into dst,
+// and counts the number of bits read.
+//
+// ErrInvalidParameter is returned if nbits is negative or dst is too small to hold nbits bits.
+// If there are fewer than nbits bits available, io.ErrUnexpectedEOF may be returned.
+func (r *CountReader) ReadBitsInto(dst []byte, nbits int) (err error) {
+	off := r.bitPos()
+	if err = readBitsInto(r, r.lsb, dst, nbits); err != nil {
+		err = wrapError("ReadBitsInto", int64(nbits), off, err)
+	}
+	return
+}
+
+// ReadBigInt reads nbits bits and returns them as a non-negative big integer,
+// and counts the number of bits read.
+//
+// ErrInvalidParameter is returned if nbits is negative.
+func (r *CountReader) ReadBigInt(nbits int) (x *big.Int, err error) {
+	off := r.bitPos()
+	if x, err = readBigInt(r, r.lsb, nbits); err != nil {
+		err = wrapError("ReadBigInt", int64(nbits), off, err)
+	}
+	return
+}
+
+// TryReadBitsInto tries to read nbits bits into dst.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBitsInto(),
+// and stores the error in the TryError field.
+func (r *CountReader) TryReadBitsInto(dst []byte, nbits int) {
+	if r.TryError == nil {
+		r.TryError = r.ReadBitsInto(dst, nbits)
+		r.checkTry()
+	}
+}
+
+// TryReadBigInt tries to read nbits bits as a non-negative big integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBigInt(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadBigInt(nbits int) (x *big.Int) {
+	if r.TryError == nil {
+		x, r.TryError = r.ReadBigInt(nbits)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteBitsFrom writes the first nbits bits of src.
+//
+// ErrInvalidParameter is returned if nbits is negative or src holds fewer than nbits bits.
+func (w *Writer) WriteBitsFrom(src []byte, nbits int) (err error) {
+	off := w.bitPos()
+	if err = writeBitsFrom(w, w.lsb, src, nbits); err != nil {
+		err = wrapError("WriteBitsFrom", int64(nbits), off, err)
+	}
+	return
+}
+
+// WriteBigInt writes the nbits lowest bits of x.
+// Bits of x in positions higher than nbits are ignored.
+//
+// ErrInvalidParameter is returned if nbits or x is negative.
+func (w *Writer) WriteBigInt(x *big.Int, nbits int) (err error) {
+	off := w.bitPos()
+	if err = writeBigInt(w, w.lsb, x, nbits); err != nil {
+		err = wrapError("WriteBigInt", int64(nbits), off, err)
+	}
+	return
+}
+
+// TryWriteBitsFrom tries to write the first nbits bits of src.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBitsFrom(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteBitsFrom(src []byte, nbits int) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBitsFrom(src, nbits)
+		w.checkTry()
+	}
+}
+
+// TryWriteBigInt tries to write the nbits lowest bits of x.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBigInt(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteBigInt(x *big.Int, nbits int) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBigInt(x, nbits)
+		w.checkTry()
+	}
+}
+
+// WriteBitsFrom writes the first nbits bits of src,
+// and counts the number of bits written.
+//
+// ErrInvalidParameter is returned if nbits is negative or src holds fewer than nbits bits.
+func (w *CountWriter) WriteBitsFrom(src []byte, nbits int) (err error) {
+	off := w.bitPos()
+	if err = writeBitsFrom(w, w.lsb, src, nbits); err != nil {
+		err = wrapError("WriteBitsFrom", int64(nbits), off, err)
+	}
+	return
+}
+
+// WriteBigInt writes the nbits lowest bits of x,
+// and counts the number of bits written.
+// Bits of x in positions higher than nbits are ignored.
+//
+// ErrInvalidParameter is returned if nbits or x is negative.
+func (w *CountWriter) WriteBigInt(x *big.Int, nbits int) (err error) {
+	off := w.bitPos()
+	if err = writeBigInt(w, w.lsb, x, nbits); err != nil {
+		err = wrapError("WriteBigInt", int64(nbits), off, err)
+	}
+	return
+}
+
+// TryWriteBitsFrom tries to write the first nbits bits of src.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBitsFrom(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteBitsFrom(src []byte, nbits int) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBitsFrom(src, nbits)
+		w.checkTry()
+	}
+}
+
+// TryWriteBigInt tries to write the nbits lowest bits of x.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBigInt(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteBigInt(x *big.Int, nbits int) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBigInt(x, nbits)
+		w.checkTry()
+	}
+}
+
+// readBitsInto reads nbits bits into dst from r.
+// lsb tells the bit order of r.
+func readBitsInto(r bitReader, lsb bool, dst []byte, nbits int) (err error) {
+	if nbits < 0 || nbits > len(dst)*8 {
+		return ErrInvalidParameter
+	}
+
+	// Whole bytes
+	n := nbits / 8
+	if _, err = io.ReadFull(r, dst[:n]); err != nil {
+		return
+	}
+
+	// Last partial byte
+	if rem := uint8(nbits % 8); rem > 0 {
+		var u uint64
+		if u, err = r.ReadBits(rem); err != nil {
+			if n > 0 {
+				err = noEOF(err)
+			}
+			return
+		}
+		if !lsb {
+			u <<= 8 - rem
+		}
+		dst[n] = byte(u)
+	}
+	return nil
+}
+
+// readBigInt reads nbits bits as a non-negative big integer from r.
+// lsb tells the bit order of r.
+func readBigInt(r bitReader, lsb bool, nbits int) (x *big.Int, err error) {
+	if nbits < 0 {
+		return nil, ErrInvalidParameter
+	}
+	buf := make([]byte, (nbits+7)/8)
+	if err = readBitsInto(r, lsb, buf, nbits); err != nil {
+		return nil, err
+	}
+
+	x = new(big.Int)
+	if lsb {
+		// First byte holds the lowest bits, big.Int needs big endian order
+		reverseBytes(buf)
+		return x.SetBytes(buf), nil
+	}
+	// Last partial byte holds the lowest bits in its highest bits
+	return x.Rsh(x.SetBytes(buf), uint(len(buf)*8-nbits)), nil
+}
+
+// writeBitsFrom writes the first nbits bits of src to w.
+// lsb tells the bit order of w.
+func writeBitsFrom(w bitWriter, lsb bool, src []byte, nbits int) (err error) {
+	if nbits < 0 || nbits > len(src)*8 {
+		return ErrInvalidParameter
+	}
+
+	// Whole bytes
+	n := nbits / 8
+	if _, err = w.Write(src[:n]); err != nil {
+		return
+	}
+
+	// Last partial byte
+	if rem := uint8(nbits % 8); rem > 0 {
+		u := uint64(src[n])
+		if !lsb {
+			u >>= 8 - rem
+		}
+		return w.WriteBits(u, rem)
+	}
+	return nil
+}
+
+// writeBigInt writes the nbits lowest bits of x to w.
+// lsb tells the bit order of w.
+func writeBigInt(w bitWriter, lsb bool, x *big.Int, nbits int) (err error) {
+	if nbits < 0 || x.Sign() < 0 {
+		return ErrInvalidParameter
+	}
+	buf := make([]byte, (nbits+7)/8)
+
+	if !lsb {
+		// Last partial byte must hold the lowest bits in its highest bits
+		x = new(big.Int).Lsh(x, uint(len(buf)*8-nbits))
+	}
+	// Lowest len(buf) bytes of x, in big endian order
+	b := x.Bytes()
+	if len(b) > len(buf) {
+		b = b[len(b)-len(buf):]
+	}
+	copy(buf[len(buf)-len(b):], b)
+	if lsb {
+		reverseBytes(buf)
+	}
+
+	return writeBitsFrom(w, lsb, buf, nbits)
+}
+
+// reverseBytes reverses the order of bytes of b.
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,210 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestBitsInto(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	// MSB: 1 + 0xab 0xcd + 101
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteBool(true))
+	eq(nil, w.WriteBitsFrom([]byte{0xab, 0xcd, 0xa0}, 19))
+	eq(nil, w.Close())
+	// 1101 0101 1110 0110 1101 0000
+	eq(true, bytes.Equal(b.Bytes(), []byte{0xd5, 0xe6, 0xd0}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	dst := make([]byte, 3)
+	eq(nil, r.ReadBitsInto(dst[:1], 1))
+	eq(true, bytes.Equal(dst, []byte{0x80, 0, 0}))
+	eq(nil, r.ReadBitsInto(dst, 19))
+	eq(true, bytes.Equal(dst, []byte{0xab, 0xcd, 0xa0}))
+
+	// LSB: 1 + 0xab 0xcd + 101
+	b = &bytes.Buffer{}
+	w = NewWriterLSB(b)
+	eq(nil, w.WriteBool(true))
+	eq(nil, w.WriteBitsFrom([]byte{0xab, 0xcd, 0x05}, 19))
+	eq(nil, w.Close())
+	eq(true, bytes.Equal(b.Bytes(), []byte{0x57, 0x9b, 0x0b}))
+
+	r = NewReaderLSB(bytes.NewBuffer(b.Bytes()))
+	eq(nil, r.ReadBitsInto(dst[:1], 1))
+	eq(true, bytes.Equal(dst, []byte{0x01, 0xcd, 0xa0}))
+	eq(nil, r.ReadBitsInto(dst, 19))
+	eq(true, bytes.Equal(dst, []byte{0xab, 0xcd, 0x05}))
+
+	// Errors
+	eq(true, errors.Is(r.ReadBitsInto(dst, -1), ErrInvalidParameter))
+	eq(true, errors.Is(r.ReadBitsInto(dst, 25), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteBitsFrom(dst, -1), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteBitsFrom(dst, 25), ErrInvalidParameter))
+	eq(nil, r.ReadBitsInto(dst, 0))
+	eq(true, errors.Is(r.ReadBitsInto(dst, 16), io.ErrUnexpectedEOF))
+
+	r = NewReader(bytes.NewBuffer([]byte{0x01}))
+	eq(true, errors.Is(r.ReadBitsInto(dst, 16), io.ErrUnexpectedEOF))
+	r = NewReader(bytes.NewBuffer([]byte{0x01}))
+	eq(true, errors.Is(r.ReadBitsInto(dst, 10), io.ErrUnexpectedEOF))
+}
+
+func TestBitsIntoChain(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	data := make([]byte, 1000)
+	rand.Read(data)
+	var lengths []int
+	for i := 0; i < 100; i++ {
+		lengths = append(lengths, rand.Intn(len(data)*8+1))
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for _, n := range lengths {
+			eq(nil, w.WriteBitsFrom(data, n))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		dst := make([]byte, len(data))
+		for _, n := range lengths {
+			for i := range dst {
+				dst[i] = 0
+			}
+			eq(nil, r.ReadBitsInto(dst, n))
+			eq(true, bytes.Equal(dst[:n/8], data[:n/8]))
+			if rem := uint(n % 8); rem > 0 {
+				mask := byte(1<<rem - 1)
+				if !lsb {
+					mask <<= 8 - rem
+				}
+				eq(data[n/8]&mask, dst[n/8])
+			}
+		}
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	x, _ := new(big.Int).SetString("123456789abcdef0fedcba9876543210f", 16) // 129 bits
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteBits(0x3, 2))
+	eq(nil, w.WriteBigInt(x, 129))
+	eq(nil, w.WriteBigInt(x, 8))   // Truncated: 0x0f
+	eq(nil, w.WriteBigInt(x, 132)) // 3 leading zeros
+	eq(nil, w.Close())
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	u, err := r.ReadBits(2)
+	eq(uint64(0x3), u)
+	eq(nil, err)
+	y, err := r.ReadBigInt(129)
+	eq(nil, err)
+	eq(0, x.Cmp(y))
+	y, err = r.ReadBigInt(8)
+	eq(nil, err)
+	eq(int64(0x0f), y.Int64())
+	y, err = r.ReadBigInt(132)
+	eq(nil, err)
+	eq(0, x.Cmp(y))
+
+	// 64-bit values must be the same as with ReadBits() / WriteBits()
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		values := make([]uint64, 100)
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for i := range values {
+			values[i] = rand.Uint64()
+			n := uint8(i%64 + 1)
+			eq(nil, w.WriteBigInt(new(big.Int).SetUint64(values[i]), int(n)))
+			eq(nil, w.WriteBits(values[i], n))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for i, v := range values {
+			n := uint8(i%64 + 1)
+			u, err := r.ReadBits(n)
+			eq(nil, err)
+			eq(v&(1<<n-1), u)
+			y, err := r.ReadBigInt(int(n))
+			eq(nil, err)
+			eq(v&(1<<n-1), y.Uint64())
+		}
+	}
+
+	// Errors
+	_, err = r.ReadBigInt(-1)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	_, err = r.ReadBigInt(2) // Only 1 padding bit left
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	_, err = r.ReadBigInt(1)
+	eq(io.EOF, err)
+	eq(true, errors.Is(w.WriteBigInt(x, -1), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteBigInt(big.NewInt(-1), 8), ErrInvalidParameter))
+}
+
+func TestBitsIntoTryCount(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteBitsFrom([]byte{0x12, 0x34}, 12))
+	eq(nil, w.WriteBigInt(big.NewInt(1000), 100))
+	eq(int64(112), w.BitsCount)
+	w.TryWriteBitsFrom([]byte{0xff}, 3)
+	w.TryWriteBigInt(big.NewInt(5), 3)
+	eq(nil, w.TryError)
+	eq(int64(118), w.BitsCount)
+	w.TryWriteBigInt(big.NewInt(-5), 3)
+	eq(true, errors.Is(w.TryError, ErrInvalidParameter))
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	dst := make([]byte, 2)
+	eq(nil, r.ReadBitsInto(dst, 12))
+	eq(true, bytes.Equal(dst, []byte{0x12, 0x30}))
+	x, err := r.ReadBigInt(100)
+	eq(nil, err)
+	eq(int64(1000), x.Int64())
+	eq(int64(112), r.BitsCount)
+	r.TryReadBitsInto(dst, 3)
+	eq(int64(5), r.TryReadBigInt(3).Int64())
+	eq(nil, r.TryError)
+	eq(byte(0xe0), dst[0])
+	eq(int64(118), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	rr.TryReadBitsInto(dst, 12)
+	eq(int64(1000), rr.TryReadBigInt(100).Int64())
+	eq(nil, rr.TryError)
+
+	ww := NewWriter(&bytes.Buffer{})
+	ww.TryWriteBitsFrom(dst, 3)
+	ww.TryWriteBigInt(big.NewInt(1), 3)
+	eq(nil, ww.TryError)
+}
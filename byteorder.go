@@ -0,0 +1,324 @@
+/*
+
+Multi-byte integers with selectable byte order.
+
+*/
+
+package bitio
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Multi-byte integers are read and written as consecutive bytes at the current
+// bit position (which does not need to be byte aligned), the bytes being
+// the same as ReadByte() would return / WriteByte() would write.
+
+// ReadUint16 reads a 16-bit unsigned integer of 2 bytes in the given byte order.
+func (r *Reader) ReadUint16(order binary.ByteOrder) (u uint16, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 2)
+	return uint16(v), wrapError("ReadUint16", 16, off, err)
+}
+
+// ReadUint32 reads a 32-bit unsigned integer of 4 bytes in the given byte order.
+func (r *Reader) ReadUint32(order binary.ByteOrder) (u uint32, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 4)
+	return uint32(v), wrapError("ReadUint32", 32, off, err)
+}
+
+// ReadUint64 reads a 64-bit unsigned integer of 8 bytes in the given byte order.
+func (r *Reader) ReadUint64(order binary.ByteOrder) (u uint64, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 8)
+	return uint64(v), wrapError("ReadUint64", 64, off, err)
+}
+
+// TryReadUint16 tries to read a 16-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUint16(order binary.ByteOrder) (u uint16) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint16(order)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadUint32 tries to read a 32-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint32(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUint32(order binary.ByteOrder) (u uint32) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint32(order)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadUint64 tries to read a 64-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint64(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUint64(order binary.ByteOrder) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint64(order)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadUint16 reads a 16-bit unsigned integer of 2 bytes in the given byte order,
+// and counts the number of bits read.
+func (r *CountReader) ReadUint16(order binary.ByteOrder) (u uint16, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 2)
+	return uint16(v), wrapError("ReadUint16", 16, off, err)
+}
+
+// ReadUint32 reads a 32-bit unsigned integer of 4 bytes in the given byte order,
+// and counts the number of bits read.
+func (r *CountReader) ReadUint32(order binary.ByteOrder) (u uint32, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 4)
+	return uint32(v), wrapError("ReadUint32", 32, off, err)
+}
+
+// ReadUint64 reads a 64-bit unsigned integer of 8 bytes in the given byte order,
+// and counts the number of bits read.
+func (r *CountReader) ReadUint64(order binary.ByteOrder) (u uint64, err error) {
+	off := r.bitPos()
+	v, err := readUint(r, r.lsb, order, 8)
+	return uint64(v), wrapError("ReadUint64", 64, off, err)
+}
+
+// TryReadUint16 tries to read a 16-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUint16(order binary.ByteOrder) (u uint16) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint16(order)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadUint32 tries to read a 32-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint32(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUint32(order binary.ByteOrder) (u uint32) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint32(order)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadUint64 tries to read a 64-bit unsigned integer in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUint64(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUint64(order binary.ByteOrder) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUint64(order)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteUint16 writes u as 2 bytes in the given byte order.
+func (w *Writer) WriteUint16(u uint16, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 2); err != nil {
+		err = wrapError("WriteUint16", 16, off, err)
+	}
+	return
+}
+
+// WriteUint32 writes u as 4 bytes in the given byte order.
+func (w *Writer) WriteUint32(u uint32, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 4); err != nil {
+		err = wrapError("WriteUint32", 32, off, err)
+	}
+	return
+}
+
+// WriteUint64 writes u as 8 bytes in the given byte order.
+func (w *Writer) WriteUint64(u uint64, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 8); err != nil {
+		err = wrapError("WriteUint64", 64, off, err)
+	}
+	return
+}
+
+// TryWriteUint16 tries to write u as 2 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint16(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUint16(u uint16, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint16(u, order)
+		w.checkTry()
+	}
+}
+
+// TryWriteUint32 tries to write u as 4 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint32(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUint32(u uint32, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint32(u, order)
+		w.checkTry()
+	}
+}
+
+// TryWriteUint64 tries to write u as 8 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint64(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUint64(u uint64, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint64(u, order)
+		w.checkTry()
+	}
+}
+
+// WriteUint16 writes u as 2 bytes in the given byte order,
+// and counts the number of bits written.
+func (w *CountWriter) WriteUint16(u uint16, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 2); err != nil {
+		err = wrapError("WriteUint16", 16, off, err)
+	}
+	return
+}
+
+// WriteUint32 writes u as 4 bytes in the given byte order,
+// and counts the number of bits written.
+func (w *CountWriter) WriteUint32(u uint32, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 4); err != nil {
+		err = wrapError("WriteUint32", 32, off, err)
+	}
+	return
+}
+
+// WriteUint64 writes u as 8 bytes in the given byte order,
+// and counts the number of bits written.
+func (w *CountWriter) WriteUint64(u uint64, order binary.ByteOrder) (err error) {
+	off := w.bitPos()
+	if err = writeUint(w, w.lsb, order, uint64(u), 8); err != nil {
+		err = wrapError("WriteUint64", 64, off, err)
+	}
+	return
+}
+
+// TryWriteUint16 tries to write u as 2 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint16(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUint16(u uint16, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint16(u, order)
+		w.checkTry()
+	}
+}
+
+// TryWriteUint32 tries to write u as 4 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint32(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUint32(u uint32, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint32(u, order)
+		w.checkTry()
+	}
+}
+
+// TryWriteUint64 tries to write u as 8 bytes in the given byte order.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUint64(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUint64(u uint64, order binary.ByteOrder) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUint64(u, order)
+		w.checkTry()
+	}
+}
+
+// readUint reads a size bytes long unsigned integer in the given byte order from r.
+// lsb tells the bit order of r.
+func readUint(r bitReader, lsb bool, order binary.ByteOrder, size int) (u uint64, err error) {
+	if u, err = r.ReadBits(uint8(8 * size)); err != nil {
+		return 0, err
+	}
+
+	native, swapped := byteOrders(lsb)
+	switch order {
+	case native:
+		return u, nil
+	case swapped:
+		return bits.ReverseBytes64(u) >> (64 - 8*uint(size)), nil
+	}
+	var buf [8]byte
+	putUint(native, buf[:], u, size)
+	return getUint(order, buf[:], size), nil
+}
+
+// writeUint writes u as size bytes in the given byte order to w.
+// lsb tells the bit order of w.
+func writeUint(w bitWriter, lsb bool, order binary.ByteOrder, u uint64, size int) (err error) {
+	native, swapped := byteOrders(lsb)
+	switch order {
+	case native:
+	case swapped:
+		u = bits.ReverseBytes64(u) >> (64 - 8*uint(size))
+	default:
+		var buf [8]byte
+		putUint(order, buf[:], u, size)
+		u = getUint(native, buf[:], size)
+	}
+	return w.WriteBits(u, uint8(8*size))
+}
+
+// byteOrders returns the byte order of multi-byte values of ReadBits() / WriteBits()
+// (big endian in highest-bits-first order, little endian in least-significant-bit-first order),
+// and the opposite byte order.
+func byteOrders(lsb bool) (native, swapped binary.ByteOrder) {
+	if lsb {
+		return binary.LittleEndian, binary.BigEndian
+	}
+	return binary.BigEndian, binary.LittleEndian
+}
+
+// putUint puts u into b as size bytes in the given byte order.
+func putUint(order binary.ByteOrder, b []byte, u uint64, size int) {
+	switch size {
+	case 2:
+		order.PutUint16(b, uint16(u))
+	case 4:
+		order.PutUint32(b, uint32(u))
+	default:
+		order.PutUint64(b, u)
+	}
+}
+
+// getUint returns the size bytes long unsigned integer of b in the given byte order.
+func getUint(order binary.ByteOrder, b []byte, size int) uint64 {
+	switch size {
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
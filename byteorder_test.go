@@ -0,0 +1,178 @@
+package bitio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+// pdpEndian is a custom byte order for testing: little endian 16-bit words in big endian order.
+type pdpEndian struct{}
+
+func (pdpEndian) Uint16(b []byte) uint16 { return binary.LittleEndian.Uint16(b) }
+
+func (pdpEndian) PutUint16(b []byte, v uint16) { binary.LittleEndian.PutUint16(b, v) }
+
+func (e pdpEndian) Uint32(b []byte) uint32 {
+	return uint32(e.Uint16(b))<<16 | uint32(e.Uint16(b[2:]))
+}
+
+func (e pdpEndian) PutUint32(b []byte, v uint32) {
+	e.PutUint16(b, uint16(v>>16))
+	e.PutUint16(b[2:], uint16(v))
+}
+
+func (e pdpEndian) Uint64(b []byte) uint64 {
+	return uint64(e.Uint32(b))<<32 | uint64(e.Uint32(b[4:]))
+}
+
+func (e pdpEndian) PutUint64(b []byte, v uint64) {
+	e.PutUint32(b, uint32(v>>32))
+	e.PutUint32(b[4:], uint32(v))
+}
+
+func (pdpEndian) String() string { return "pdpEndian" }
+
+func TestByteOrder(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e}
+	orders := []binary.ByteOrder{binary.BigEndian, binary.LittleEndian, pdpEndian{}}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		for _, order := range orders {
+			for shift := uint8(0); shift < 8; shift++ {
+				// Bytes written by WriteByte() must be read by ReadUintXX()
+				b := &bytes.Buffer{}
+				w := newWriter(b)
+				eq(nil, w.WriteBits(0x55, shift))
+				for _, c := range data {
+					eq(nil, w.WriteByte(c))
+				}
+				eq(nil, w.Close())
+
+				r := newReader(bytes.NewBuffer(b.Bytes()))
+				expEq(uint64(0x55) & (1<<shift - 1))(r.ReadBits(shift))
+				expEq(order.Uint16(data))(r.ReadUint16(order))
+				expEq(order.Uint32(data[2:]))(r.ReadUint32(order))
+				expEq(order.Uint64(data[6:]))(r.ReadUint64(order))
+
+				// Values written by WriteUintXX() must be read by ReadByte()
+				b = &bytes.Buffer{}
+				w = newWriter(b)
+				eq(nil, w.WriteBits(0x55, shift))
+				eq(nil, w.WriteUint16(order.Uint16(data), order))
+				eq(nil, w.WriteUint32(order.Uint32(data[2:]), order))
+				eq(nil, w.WriteUint64(order.Uint64(data[6:]), order))
+				eq(nil, w.Close())
+
+				r = newReader(bytes.NewBuffer(b.Bytes()))
+				expEq(uint64(0x55) & (1<<shift - 1))(r.ReadBits(shift))
+				for _, c := range data {
+					expEq(c)(r.ReadByte())
+				}
+			}
+		}
+	}
+}
+
+func TestByteOrderTryCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteBool(true))
+	eq(nil, w.WriteUint16(0x1234, binary.LittleEndian))
+	eq(nil, w.WriteUint32(0x12345678, binary.BigEndian))
+	eq(nil, w.WriteUint64(0x123456789abcdef0, binary.LittleEndian))
+	eq(int64(113), w.BitsCount)
+	w.TryWriteUint16(1, binary.BigEndian)
+	w.TryWriteUint32(2, binary.BigEndian)
+	w.TryWriteUint64(3, binary.BigEndian)
+	eq(nil, w.TryError)
+	eq(int64(225), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(true)(r.ReadBool())
+	expEq(uint16(0x1234))(r.ReadUint16(binary.LittleEndian))
+	expEq(uint32(0x12345678))(r.ReadUint32(binary.BigEndian))
+	expEq(uint64(0x123456789abcdef0))(r.ReadUint64(binary.LittleEndian))
+	eq(int64(113), r.BitsCount)
+	eq(uint16(1), r.TryReadUint16(binary.BigEndian))
+	eq(uint32(2), r.TryReadUint32(binary.BigEndian))
+	eq(uint64(3), r.TryReadUint64(binary.BigEndian))
+	eq(nil, r.TryError)
+	eq(int64(225), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(true, rr.TryReadBool())
+	eq(uint16(0x1234), rr.TryReadUint16(binary.LittleEndian))
+	eq(uint32(0x12345678), rr.TryReadUint32(binary.BigEndian))
+	eq(uint64(0x123456789abcdef0), rr.TryReadUint64(binary.LittleEndian))
+	eq(uint16(1), rr.TryReadUint16(binary.BigEndian))
+	eq(uint32(2), rr.TryReadUint32(binary.BigEndian))
+	eq(uint64(3), rr.TryReadUint64(binary.BigEndian))
+	eq(nil, rr.TryError)
+	rr.TryReadUint16(binary.BigEndian)
+	eq(true, rr.TryError != nil)
+
+	ww := NewWriter(&bytes.Buffer{})
+	ww.TryWriteUint16(1, binary.BigEndian)
+	ww.TryWriteUint32(2, binary.BigEndian)
+	ww.TryWriteUint64(3, binary.BigEndian)
+	eq(nil, ww.TryError)
+}
+
+func BenchmarkReadUint32(b *testing.B) {
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+
+	b.Run("bitio", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := NewReader(bytes.NewReader(data))
+			for j := 0; j < len(data)/4; j++ {
+				r.ReadUint32(binary.LittleEndian)
+			}
+		}
+	})
+	b.Run("encoding/binary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var u uint32
+			r := bytes.NewReader(data)
+			for j := 0; j < len(data)/4; j++ {
+				binary.Read(r, binary.LittleEndian, &u)
+			}
+		}
+	})
+}
+
+func BenchmarkWriteUint32(b *testing.B) {
+	const count = 1 << 18
+
+	b.Run("bitio", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			w := NewWriter(ioutil.Discard)
+			for j := uint32(0); j < count; j++ {
+				w.WriteUint32(j, binary.LittleEndian)
+			}
+			w.Close()
+		}
+	})
+	b.Run("encoding/binary", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := uint32(0); j < count; j++ {
+				binary.Write(ioutil.Discard, binary.LittleEndian, j)
+			}
+		}
+	})
+}
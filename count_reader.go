@@ -7,6 +7,7 @@ CountReader implementation.
 package bitio
 
 import (
+	"errors"
 	"io"
 )
 
@@ -26,6 +27,12 @@ func NewCountReader(in io.Reader) *CountReader {
 	return &CountReader{NewReader(in), 0}
 }
 
+// NewCountReaderLSB returns a new CountReader using the specified io.Reader as
+// the input (source), which uses least-significant-bit-first order.
+func NewCountReaderLSB(in io.Reader) *CountReader {
+	return &CountReader{NewReaderLSB(in), 0}
+}
+
 // Read reads up to len(p) bytes (8 * len(p) bits) from the underlying reader,
 // and counts the number of bits read.
 //
@@ -40,10 +47,16 @@ func (r *CountReader) Read(p []byte) (n int, err error) {
 }
 
 // ReadBits reads n bits and returns them as the lowest n bits of u.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+//
+// If no bits are available, io.EOF is returned. If fewer than n bits are available,
+// they are consumed and returned in a *PartialReadError (wrapped in a *BitError).
 func (r *CountReader) ReadBits(n uint8) (u uint64, err error) {
 	u, err = r.Reader.ReadBits(n)
 	if err == nil {
 		r.BitsCount += int64(n)
+	} else if pe := (*PartialReadError)(nil); errors.As(err, &pe) {
+		r.BitsCount += int64(pe.Bits)
 	}
 	return
 }
@@ -68,6 +81,18 @@ func (r *CountReader) ReadBool() (b bool, err error) {
 	return
 }
 
+// SkipBits skips (discards) the next n bits, and counts the number of skipped bits.
+//
+// Unlike ReadBits(), n is not limited to 64. If the source implements io.Seeker,
+// whole bytes are skipped by seeking instead of reading them. Note that in this
+// case skipping past the end of the input is not detected until the next read.
+func (r *CountReader) SkipBits(n int64) (err error) {
+	var skipped int64
+	skipped, err = r.Reader.skipBits(n)
+	r.BitsCount += skipped
+	return
+}
+
 // Align aligns the bit stream to a byte boundary,
 // so next read will read/use data from the next byte.
 // Returns the number of unread / skipped bits.
@@ -84,6 +109,7 @@ func (r *CountReader) Align() (skipped uint8) {
 func (r *CountReader) TryRead(p []byte) (n int) {
 	if r.TryError == nil {
 		n, r.TryError = r.Read(p)
+		r.checkTry()
 	}
 	return
 }
@@ -95,6 +121,7 @@ func (r *CountReader) TryRead(p []byte) (n int) {
 func (r *CountReader) TryReadBits(n uint8) (u uint64) {
 	if r.TryError == nil {
 		u, r.TryError = r.ReadBits(n)
+		r.checkTry()
 	}
 	return
 }
@@ -106,6 +133,7 @@ func (r *CountReader) TryReadBits(n uint8) (u uint64) {
 func (r *CountReader) TryReadByte() (b byte) {
 	if r.TryError == nil {
 		b, r.TryError = r.ReadByte()
+		r.checkTry()
 	}
 	return
 }
@@ -117,6 +145,18 @@ func (r *CountReader) TryReadByte() (b byte) {
 func (r *CountReader) TryReadBool() (b bool) {
 	if r.TryError == nil {
 		b, r.TryError = r.ReadBool()
+		r.checkTry()
 	}
 	return
 }
+
+// TrySkipBits tries to skip the next n bits.
+//
+// If there was a previous TryError, it does nothing. Else it calls SkipBits(),
+// and stores the error in the TryError field.
+func (r *CountReader) TrySkipBits(n int64) {
+	if r.TryError == nil {
+		r.TryError = r.SkipBits(n)
+		r.checkTry()
+	}
+}
@@ -30,6 +30,16 @@ func NewCountWriter(out io.Writer) *CountWriter {
 	return &CountWriter{NewWriter(out), 0}
 }
 
+// NewCountWriterLSB returns a new CountWriter using the specified io.Writer as
+// the output, which uses least-significant-bit-first order.
+//
+// Must be closed in order to flush cached data.
+// If you can't or don't want to close it, flushing data can also be forced
+// by calling Align().
+func NewCountWriterLSB(out io.Writer) *CountWriter {
+	return &CountWriter{NewWriterLSB(out), 0}
+}
+
 // Write writes len(p) bytes (8 * len(p) bits) to the underlying writer.
 //
 // Write implements io.Writer, and gives a byte-level interface to the bit stream.
@@ -44,6 +54,7 @@ func (w *CountWriter) Write(p []byte) (n int, err error) {
 
 // WriteBits writes out the n lowest bits of r.
 // Bits of r in positions higher than n are ignored.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
 //
 // For example:
 //   err := w.WriteBits(0x1234, 8)
@@ -51,13 +62,15 @@ func (w *CountWriter) Write(p []byte) (n int, err error) {
 // is equivalent to:
 //   err := w.WriteBits(0x34, 8)
 func (w *CountWriter) WriteBits(r uint64, n uint8) (err error) {
-	// if r would have bits set at n or higher positions (zero indexed),
-	// WriteBitsUnsafe's implementation could "corrupt" bits in cache.
-	// That is not acceptable. To be on the safe side, mask out higher bits:
-	return w.WriteBitsUnsafe((r & (1<<n - 1)), n)
+	err = w.Writer.WriteBits(r, n)
+	if err == nil {
+		w.BitsCount += int64(n)
+	}
+	return
 }
 
 // WriteBitsUnsafe writes out the n lowest bits of r.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
 //
 // r must not have bits set at n or higher positions (zero indexed).
 // If r might not satisfy this, a mask must be explicitly applied
@@ -119,6 +132,7 @@ func (w *CountWriter) Align() (skipped uint8, err error) {
 func (w *CountWriter) TryWrite(p []byte) (n int) {
 	if w.TryError == nil {
 		n, w.TryError = w.Write(p)
+		w.checkTry()
 	}
 	return
 }
@@ -130,6 +144,7 @@ func (w *CountWriter) TryWrite(p []byte) (n int) {
 func (w *CountWriter) TryWriteBits(r uint64, n uint8) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBits(r, n)
+		w.checkTry()
 	}
 }
 
@@ -140,6 +155,7 @@ func (w *CountWriter) TryWriteBits(r uint64, n uint8) {
 func (w *CountWriter) TryWriteBitsUnsafe(r uint64, n uint8) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBitsUnsafe(r, n)
+		w.checkTry()
 	}
 }
 
@@ -150,6 +166,7 @@ func (w *CountWriter) TryWriteBitsUnsafe(r uint64, n uint8) {
 func (w *CountWriter) TryWriteByte(b byte) {
 	if w.TryError == nil {
 		w.TryError = w.WriteByte(b)
+		w.checkTry()
 	}
 }
 
@@ -160,6 +177,7 @@ func (w *CountWriter) TryWriteByte(b byte) {
 func (w *CountWriter) TryWriteBool(b bool) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBool(b)
+		w.checkTry()
 	}
 }
 
@@ -170,6 +188,7 @@ func (w *CountWriter) TryWriteBool(b bool) {
 func (w *CountWriter) TryAlign() (skipped uint8) {
 	if w.TryError == nil {
 		skipped, w.TryError = w.Align()
+		w.checkTry()
 	}
 	return
 }
@@ -180,8 +199,11 @@ func (w *CountWriter) TryAlign() (skipped uint8) {
 // Close implements io.Closer.
 func (w *CountWriter) Close() (err error) {
 	// Make sure cached bits are flushed:
-	if _, err = w.Align(); err != nil {
-		return
+	off := w.bitPos()
+	skipped, err := w.align()
+	w.BitsCount += int64(skipped)
+	if err != nil {
+		return wrapError("Close", 0, off, err)
 	}
 
 	return nil
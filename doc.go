@@ -18,9 +18,28 @@ you best performance if the underlying io.Reader and io.Writer are aligned to a
 byte boundary alignment by calling the Align() method of Reader and Writer. As an extra,
 io.ByteReader and io.ByteWriter are also implemented.
 
+The BitReader and BitWriter interfaces describe the common method set of Reader and CountReader,
+and of Writer and CountWriter, so functions can accept any of them (or a test fake).
+
+Multi-byte integers can be read and written in a given byte order (binary.ByteOrder) at any bit position
+using ReadUint16(), ReadUint32(), ReadUint64() and WriteUint16(), WriteUint32(), WriteUint64().
+
+Bit strings longer than 64 bits (e.g. UUIDs, hashes or bitmaps) can be read and written at any bit position
+using ReadBitsInto() and WriteBitsFrom() (into / from byte slices), and ReadBigInt() and WriteBigInt() (as *big.Int values).
+
+Reader.BitPos() returns the bit position of the next read, and Reader.SeekBits() sets it in io.Seeker style
+(allowing random access to bit-packed data), if the source implements io.Seeker.
+
+ReaderAt is a stateless random-access bit reader over an io.ReaderAt or a byte slice (see NewReaderAt() and NewReaderAtBytes()).
+Its ReadBitsAt() and ReadBoolAt() methods read bits at arbitrary bit offsets, and are safe for concurrent use by multiple goroutines.
+
+BitBuffer is an in-memory buffer of bits (analogous to bytes.Buffer) with independent read and write cursors.
+Besides reading and writing bits, bits at arbitrary offsets can be accessed and patched (e.g. a length field written earlier)
+using GetBit(), SetBit(), GetBits() and SetBits().
+
 # Bit order
 
-The more general highest-bits-first order is used. So for example if the input provides the bytes 0x8f and 0x55:
+The more general highest-bits-first order is used by default. So for example if the input provides the bytes 0x8f and 0x55:
 
 	HEXA    8    f     5    5
 	BINARY  1000 1111  0101 0101
@@ -45,6 +64,20 @@ Writing the above values would result in the same sequence of bytes:
 	err = w.Close()
 	// b will hold the bytes: 0x8f and 0x55
 
+Many formats (e.g. DEFLATE, GIF LZW) pack bits least-significant-bit-first.
+Use NewReaderLSB() and NewWriterLSB() (or NewCountReaderLSB() and NewCountWriterLSB())
+to create a Reader / Writer using this order. The same input would then be read as:
+
+	HEXA    8    f     5    5
+	BINARY  1000 1111  0101 0101
+	        cbbb aaaa  dddd ddcc
+
+	r := NewReaderLSB(bytes.NewBuffer([]byte{0x8f, 0x55}))
+	a, err := r.ReadBits(4) //   1111 = 0x0f
+	b, err := r.ReadBits(3) //    000 = 0x00
+	c, err := r.ReadBits(3) //    011 = 0x03
+	d, err := r.ReadBits(6) // 010101 = 0x15
+
 # Error handling
 
 All ReadXXX() and WriteXXX() methods return an error which you are expected to handle.
@@ -90,6 +123,75 @@ And similarly:
 	err = w.Close()
 	// b will hold the bytes: 0x8f and 0x55
 
+If the TryPanic field of a Reader / Writer is set, the first failing TryXXX() method panics instead of
+only storing the error, so deeply nested parsers can abort early. Catch() recovers from this panic and returns the error:
+
+	r := NewReader(bytes.NewBuffer([]byte{0x8f, 0x55}))
+	r.TryPanic = true
+	err := Catch(func() {
+	    a := r.TryReadBits(4)
+	    b := r.TryReadUE(0)
+	    // ...
+	})
+	if err != nil {
+	    // Handle error
+	}
+
+Reading at the end of the input follows the conventions of the io package: io.EOF is returned
+only if no bits are available. If ReadBits() finds fewer bits than requested, it consumes them
+and returns them in a *PartialReadError (which wraps io.ErrUnexpectedEOF or the error of the underlying reader).
+Similarly, reading a code (e.g. unary, Exp-Golomb or Elias) that is cut off by the end of the input
+results in io.ErrUnexpectedEOF.
+
+Errors of Reader and Writer (and CountReader and CountWriter) operations other than io.EOF are wrapped
+in a *BitError, which records the failed operation, the requested number of bits and the absolute bit offset
+at which the operation started. This includes codes and other composite values (e.g. ReadUE() or WriteSigned()),
+which report themselves and the offset of their first bit, whichever part of them failed.
+Use errors.Is() and errors.As() to inspect the underlying error (e.g. ErrOverflow):
+
+	u, err := r.ReadBits(12)
+	if err != nil {
+	    log.Println(err) // e.g. bitio: ReadBits(12) failed at bit 1234567890: unexpected EOF
+	    var be *BitError
+	    if errors.As(err, &be) {
+	        // be.Op, be.Width, be.Offset
+	    }
+	}
+
+# Signed integers
+
+Reader.ReadSigned() reads an n-bit two's complement signed integer and sign extends it to int64,
+Writer.WriteSigned() writes the lowest n bits of an int64 value. Values that do not fit into n bits
+are truncated, unless the Strict field of the Writer is set, in which case ErrOverflow is returned.
+
+Sign-magnitude and biased (offset binary, excess-K) integers of arbitrary bit width are also supported:
+ReadSignMagnitude(), ReadBiased(), WriteSignMagnitude(), WriteBiased().
+
+Zigzag encoding maps signed values with small magnitude to small unsigned values (0, -1, 1, -2, 2... to 0, 1, 2, 3, 4...).
+ReadZigzag() and WriteZigzag() read and write zigzag encoded values in n bits, ReadZigzagUE() and WriteZigzagUE() as Exponential-Golomb codes,
+and ReadVarint() and WriteVarint() as varints. ZigzagEncode() and ZigzagDecode() are also exported.
+
+# Floating point numbers
+
+IEEE 754 floating point numbers can be read and written at any bit position: ReadFloat32(), ReadFloat64(), ReadFloat16(),
+ReadBFloat16() and their Write counterparts. Half precision (binary16) and bfloat16 values are converted from / to float32,
+rounding to nearest, ties to even. NaN payloads are preserved.
+
+Custom (mini) floating point formats can be described by FloatFormat (sign, exponent and mantissa bits, bias,
+denormal support, representation of infinities and NaNs), and read and written as float64 using ReadFloat() and WriteFloat().
+Frequently used formats are predefined, e.g. FloatE4M3, FloatE5M2 (OCP FP8) and FloatUnsigned11, FloatUnsigned10 (packed GPU formats).
+
+# Codes
+
+Besides raw bits, Reader and Writer (and CountReader and CountWriter) can read and write
+frequently used variable-length codes:
+
+  - Unary codes: ReadUnary(), WriteUnary()
+  - Exponential-Golomb codes: ReadUE(), ReadSE(), WriteUE(), WriteSE()
+  - Golomb and Golomb-Rice codes: ReadGolomb(), ReadRice(), WriteGolomb(), WriteRice() and their signed variants
+  - Elias gamma, delta and omega codes: ReadEliasGamma(), ReadEliasDelta(), ReadEliasOmega(), WriteEliasGamma(), WriteEliasDelta(), WriteEliasOmega()
+  - Varints (LEB128, Protocol Buffers), even if the stream is not byte aligned: ReadUvarint(), ReadVarint(), ReadSLEB128(), WriteUvarint(), WriteVarint(), WriteSLEB128()
+
 # Number of processed bits
 
 For performance reasons, Reader and Writer do not keep track of the number of read or written bits.
@@ -0,0 +1,373 @@
+/*
+
+Elias gamma, delta and omega coding.
+
+*/
+
+package bitio
+
+import "math/bits"
+
+// Elias codes represent positive integers. Writing 0 results in ErrOverflow.
+
+// ReadEliasGamma reads an Elias gamma code.
+func (r *Reader) ReadEliasGamma() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasGamma(r); err != nil {
+		err = wrapError("ReadEliasGamma", 0, off, err)
+	}
+	return
+}
+
+// ReadEliasDelta reads an Elias delta code.
+func (r *Reader) ReadEliasDelta() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasDelta(r); err != nil {
+		err = wrapError("ReadEliasDelta", 0, off, err)
+	}
+	return
+}
+
+// ReadEliasOmega reads an Elias omega code.
+func (r *Reader) ReadEliasOmega() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasOmega(r); err != nil {
+		err = wrapError("ReadEliasOmega", 0, off, err)
+	}
+	return
+}
+
+// TryReadEliasGamma tries to read an Elias gamma code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasGamma(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadEliasGamma() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasGamma()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadEliasDelta tries to read an Elias delta code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasDelta(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadEliasDelta() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasDelta()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadEliasOmega tries to read an Elias omega code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasOmega(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadEliasOmega() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasOmega()
+		r.checkTry()
+	}
+	return
+}
+
+// ReadEliasGamma reads an Elias gamma code,
+// and counts the number of bits read.
+func (r *CountReader) ReadEliasGamma() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasGamma(r); err != nil {
+		err = wrapError("ReadEliasGamma", 0, off, err)
+	}
+	return
+}
+
+// ReadEliasDelta reads an Elias delta code,
+// and counts the number of bits read.
+func (r *CountReader) ReadEliasDelta() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasDelta(r); err != nil {
+		err = wrapError("ReadEliasDelta", 0, off, err)
+	}
+	return
+}
+
+// ReadEliasOmega reads an Elias omega code,
+// and counts the number of bits read.
+func (r *CountReader) ReadEliasOmega() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readEliasOmega(r); err != nil {
+		err = wrapError("ReadEliasOmega", 0, off, err)
+	}
+	return
+}
+
+// TryReadEliasGamma tries to read an Elias gamma code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasGamma(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadEliasGamma() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasGamma()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadEliasDelta tries to read an Elias delta code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasDelta(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadEliasDelta() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasDelta()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadEliasOmega tries to read an Elias omega code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadEliasOmega(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadEliasOmega() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadEliasOmega()
+		r.checkTry()
+	}
+	return
+}
+
+// WriteEliasGamma writes u as an Elias gamma code.
+func (w *Writer) WriteEliasGamma(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasGamma(w, u); err != nil {
+		err = wrapError("WriteEliasGamma", 0, off, err)
+	}
+	return
+}
+
+// WriteEliasDelta writes u as an Elias delta code.
+func (w *Writer) WriteEliasDelta(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasDelta(w, u); err != nil {
+		err = wrapError("WriteEliasDelta", 0, off, err)
+	}
+	return
+}
+
+// WriteEliasOmega writes u as an Elias omega code.
+func (w *Writer) WriteEliasOmega(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasOmega(w, u); err != nil {
+		err = wrapError("WriteEliasOmega", 0, off, err)
+	}
+	return
+}
+
+// TryWriteEliasGamma tries to write u as an Elias gamma code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasGamma(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteEliasGamma(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasGamma(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteEliasDelta tries to write u as an Elias delta code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasDelta(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteEliasDelta(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasDelta(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteEliasOmega tries to write u as an Elias omega code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasOmega(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteEliasOmega(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasOmega(u)
+		w.checkTry()
+	}
+}
+
+// WriteEliasGamma writes u as an Elias gamma code,
+// and counts the number of bits written.
+func (w *CountWriter) WriteEliasGamma(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasGamma(w, u); err != nil {
+		err = wrapError("WriteEliasGamma", 0, off, err)
+	}
+	return
+}
+
+// WriteEliasDelta writes u as an Elias delta code,
+// and counts the number of bits written.
+func (w *CountWriter) WriteEliasDelta(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasDelta(w, u); err != nil {
+		err = wrapError("WriteEliasDelta", 0, off, err)
+	}
+	return
+}
+
+// WriteEliasOmega writes u as an Elias omega code,
+// and counts the number of bits written.
+func (w *CountWriter) WriteEliasOmega(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeEliasOmega(w, u); err != nil {
+		err = wrapError("WriteEliasOmega", 0, off, err)
+	}
+	return
+}
+
+// TryWriteEliasGamma tries to write u as an Elias gamma code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasGamma(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteEliasGamma(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasGamma(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteEliasDelta tries to write u as an Elias delta code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasDelta(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteEliasDelta(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasDelta(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteEliasOmega tries to write u as an Elias omega code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteEliasOmega(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteEliasOmega(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteEliasOmega(u)
+		w.checkTry()
+	}
+}
+
+// readEliasGamma reads an Elias gamma code from r.
+func readEliasGamma(r bitReader) (u uint64, err error) {
+	// Number of bits following the leading 1 bit, coded in unary
+	n, err := r.ReadUnary(true, 63)
+	if err != nil {
+		return 0, err
+	}
+	if u, err = r.ReadBits(uint8(n)); err != nil {
+		return 0, noEOF(err)
+	}
+	return 1<<uint(n) | u, nil
+}
+
+// readEliasDelta reads an Elias delta code from r.
+func readEliasDelta(r bitReader) (u uint64, err error) {
+	// Number of bits of the value, coded in Elias gamma
+	n, err := readEliasGamma(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > 64 {
+		return 0, ErrOverflow
+	}
+	if u, err = r.ReadBits(uint8(n - 1)); err != nil {
+		return 0, noEOF(err)
+	}
+	return 1<<(n-1) | u, nil
+}
+
+// readEliasOmega reads an Elias omega code from r.
+func readEliasOmega(r bitReader) (u uint64, err error) {
+	u = 1
+	for {
+		b, err := r.ReadBool()
+		if err != nil {
+			if u > 1 {
+				err = noEOF(err) // Groups have been read
+			}
+			return 0, err
+		}
+		if !b {
+			return u, nil
+		}
+		// A group of u+1 bits, the first (1) bit is already read
+		if u > 63 {
+			return 0, ErrOverflow
+		}
+		var g uint64
+		if g, err = r.ReadBits(uint8(u)); err != nil {
+			return 0, noEOF(err)
+		}
+		u = 1<<u | g
+	}
+}
+
+// writeEliasGamma writes u as an Elias gamma code to w.
+func writeEliasGamma(w bitWriter, u uint64) (err error) {
+	if u == 0 {
+		return ErrOverflow
+	}
+	n := uint8(bits.Len64(u)) - 1 // number of bits following the leading 1 bit
+	if err = w.WriteBits(0, n); err != nil {
+		return
+	}
+	// Leading 1 bit separately, so codes are read back properly in both bit orders
+	if err = w.WriteBool(true); err != nil {
+		return
+	}
+	return w.WriteBits(u, n)
+}
+
+// writeEliasDelta writes u as an Elias delta code to w.
+func writeEliasDelta(w bitWriter, u uint64) (err error) {
+	if u == 0 {
+		return ErrOverflow
+	}
+	n := uint8(bits.Len64(u))
+	if err = writeEliasGamma(w, uint64(n)); err != nil {
+		return
+	}
+	// The leading 1 bit of u is omitted
+	return w.WriteBits(u, n-1)
+}
+
+// writeEliasOmega writes u as an Elias omega code to w.
+func writeEliasOmega(w bitWriter, u uint64) (err error) {
+	if u == 0 {
+		return ErrOverflow
+	}
+	// Groups are written in reverse order of their calculation:
+	// each group is preceded by the group coding its length minus 1.
+	var groups [4]uint64 // At most 4 groups, e.g. of 64, 6, 3 and 2 bits
+	count := 0
+	for ; u > 1; u = uint64(bits.Len64(u)) - 1 {
+		groups[count] = u
+		count++
+	}
+	for i := count - 1; i >= 0; i-- {
+		g := groups[i]
+		// Leading 1 bit separately, so codes are read back properly in both bit orders
+		if err = w.WriteBool(true); err != nil {
+			return
+		}
+		if err = w.WriteBits(g, uint8(bits.Len64(g))-1); err != nil {
+			return
+		}
+	}
+	return w.WriteBool(false)
+}
@@ -0,0 +1,173 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestElias(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteEliasGamma(1))   // 1
+	eq(nil, w.WriteEliasGamma(2))   // 010
+	eq(nil, w.WriteEliasGamma(3))   // 011
+	eq(nil, w.WriteEliasGamma(4))   // 00100
+	eq(nil, w.WriteEliasDelta(17))  // 001010001
+	eq(nil, w.WriteEliasOmega(100)) // 1011011001000
+	eq(nil, w.WriteEliasOmega(1))   // 0
+	eq(nil, w.WriteEliasOmega(2))   // 100
+	eq(nil, w.Close())
+	// 1010 0110 0100 0010 1000 1101 1011 0010 0001 0000
+	eq(true, bytes.Equal(b.Bytes(), []byte{0xa6, 0x42, 0x8d, 0xb2, 0x10}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(1))(r.ReadEliasGamma())
+	expEq(uint64(2))(r.ReadEliasGamma())
+	expEq(uint64(3))(r.ReadEliasGamma())
+	expEq(uint64(4))(r.ReadEliasGamma())
+	expEq(uint64(17))(r.ReadEliasDelta())
+	expEq(uint64(100))(r.ReadEliasOmega())
+	expEq(uint64(1))(r.ReadEliasOmega())
+	expEq(uint64(2))(r.ReadEliasOmega())
+	expEq(uint64(0))(r.ReadBits(2))
+	_, err := r.ReadEliasOmega()
+	eq(io.EOF, err)
+}
+
+func TestEliasChain(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	values := []uint64{1, 2, 3, 1 << 32, 1<<63 - 1, 1 << 63, math.MaxUint64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Uint64()>>uint(rand.Intn(64))|1)
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for _, u := range values {
+			eq(nil, w.WriteEliasGamma(u))
+			eq(nil, w.WriteEliasDelta(u))
+			eq(nil, w.WriteEliasOmega(u))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, u := range values {
+			expEq(u)(r.ReadEliasGamma())
+			expEq(u)(r.ReadEliasDelta())
+			expEq(u)(r.ReadEliasOmega())
+		}
+	}
+}
+
+func TestEliasErrors(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	w := NewWriter(&bytes.Buffer{})
+	eq(true, errors.Is(w.WriteEliasGamma(0), ErrOverflow))
+	eq(true, errors.Is(w.WriteEliasDelta(0), ErrOverflow))
+	eq(true, errors.Is(w.WriteEliasOmega(0), ErrOverflow))
+
+	// 64 leading zeros
+	r := NewReader(bytes.NewBuffer(make([]byte, 9)))
+	_, err := r.ReadEliasGamma()
+	eq(true, errors.Is(err, ErrOverflow))
+
+	// Length 65: 0000001 000001
+	b := &bytes.Buffer{}
+	w = NewWriter(b)
+	eq(nil, w.WriteEliasGamma(65))
+	eq(nil, w.Close())
+	r = NewReader(bytes.NewBuffer(b.Bytes()))
+	_, err = r.ReadEliasDelta()
+	eq(true, errors.Is(err, ErrOverflow))
+
+	// Groups: 1 1, 1 111, 1 111111111111111, 1 (65536 bits)
+	r = NewReader(bytes.NewBuffer([]byte{0xff, 0xff, 0xff}))
+	_, err = r.ReadEliasOmega()
+	eq(true, errors.Is(err, ErrOverflow))
+
+	// No partial value is returned along with an error
+	r = NewReader(bytes.NewBuffer(nil))
+	u, err := r.ReadEliasOmega()
+	eq(uint64(0), u)
+	eq(io.EOF, err)
+	// Groups: 1 1, 1 111, 1 and 1 of 15 bits
+	r = NewReader(bytes.NewBuffer([]byte{0xff}))
+	u, err = r.ReadEliasOmega()
+	eq(uint64(0), u)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestEliasTry(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.TryWriteEliasGamma(5)
+	w.TryWriteEliasDelta(6)
+	w.TryWriteEliasOmega(7)
+	eq(nil, w.TryError)
+	w.TryWriteEliasGamma(0)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	w.TryWriteEliasDelta(1)
+	w.TryWriteEliasOmega(1)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	eq(nil, w.Close())
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(uint64(5), r.TryReadEliasGamma())
+	eq(uint64(6), r.TryReadEliasDelta())
+	eq(uint64(7), r.TryReadEliasOmega())
+	eq(nil, r.TryError)
+	r.TryReadEliasGamma()
+	eq(io.EOF, r.TryError)
+	eq(uint64(0), r.TryReadEliasDelta())
+	eq(uint64(0), r.TryReadEliasOmega())
+}
+
+func TestEliasCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteEliasGamma(17)) // 9 bits
+	eq(int64(9), w.BitsCount)
+	eq(nil, w.WriteEliasDelta(17)) // 9 bits
+	eq(int64(18), w.BitsCount)
+	eq(nil, w.WriteEliasOmega(17)) // 11 bits
+	eq(int64(29), w.BitsCount)
+	w.TryWriteEliasGamma(1)
+	w.TryWriteEliasDelta(1)
+	w.TryWriteEliasOmega(1)
+	eq(nil, w.TryError)
+	eq(int64(32), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(17))(r.ReadEliasGamma())
+	eq(int64(9), r.BitsCount)
+	expEq(uint64(17))(r.ReadEliasDelta())
+	eq(int64(18), r.BitsCount)
+	expEq(uint64(17))(r.ReadEliasOmega())
+	eq(int64(29), r.BitsCount)
+	eq(uint64(1), r.TryReadEliasGamma())
+	eq(uint64(1), r.TryReadEliasDelta())
+	eq(uint64(1), r.TryReadEliasOmega())
+	eq(nil, r.TryError)
+	eq(int64(32), r.BitsCount)
+}
@@ -0,0 +1,292 @@
+/*
+
+Exponential-Golomb coding.
+
+*/
+
+package bitio
+
+import (
+	"math"
+	"math/bits"
+)
+
+// maxExpGolombZeros is the max number of leading zeros of an Exp-Golomb code.
+const maxExpGolombZeros = 31
+
+// ReadUE reads an unsigned Exponential-Golomb code of order k.
+// ue(v) of H.264 / H.265 is the code of order 0.
+//
+// Codes with more than 31 leading zeros (and codes whose value does not fit
+// into an uint64) result in ErrOverflow.
+// k must not be greater than 63, else ErrInvalidParameter is returned.
+func (r *Reader) ReadUE(k uint8) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readUE(r, k); err != nil {
+		err = wrapError("ReadUE", 0, off, err)
+	}
+	return
+}
+
+// ReadSE reads a signed Exponential-Golomb code of order k.
+// se(v) of H.264 / H.265 is the code of order 0.
+//
+// Positive values are mapped to odd, others to even code numbers:
+// 0, 1, -1, 2, -2... are represented by the code numbers 0, 1, 2, 3, 4...
+func (r *Reader) ReadSE(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSE(r, k); err != nil {
+		err = wrapError("ReadSE", 0, off, err)
+	}
+	return
+}
+
+// TryReadUE tries to read an unsigned Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUE(k uint8) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadSE tries to read a signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadSE(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadUE reads an unsigned Exponential-Golomb code of order k,
+// and counts the number of bits read.
+// ue(v) of H.264 / H.265 is the code of order 0.
+//
+// Codes with more than 31 leading zeros (and codes whose value does not fit
+// into an uint64) result in ErrOverflow.
+// k must not be greater than 63, else ErrInvalidParameter is returned.
+func (r *CountReader) ReadUE(k uint8) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readUE(r, k); err != nil {
+		err = wrapError("ReadUE", 0, off, err)
+	}
+	return
+}
+
+// ReadSE reads a signed Exponential-Golomb code of order k,
+// and counts the number of bits read.
+// se(v) of H.264 / H.265 is the code of order 0.
+//
+// Positive values are mapped to odd, others to even code numbers:
+// 0, 1, -1, 2, -2... are represented by the code numbers 0, 1, 2, 3, 4...
+func (r *CountReader) ReadSE(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSE(r, k); err != nil {
+		err = wrapError("ReadSE", 0, off, err)
+	}
+	return
+}
+
+// TryReadUE tries to read an unsigned Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUE(k uint8) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadSE tries to read a signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadSE(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteUE writes u as an unsigned Exponential-Golomb code of order k.
+// ue(v) of H.264 / H.265 is the code of order 0.
+//
+// Values whose code would have more than 31 leading zeros result in ErrOverflow.
+// k must not be greater than 63, else ErrInvalidParameter is returned.
+func (w *Writer) WriteUE(u uint64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeUE(w, u, k); err != nil {
+		err = wrapError("WriteUE", 0, off, err)
+	}
+	return
+}
+
+// WriteSE writes v as a signed Exponential-Golomb code of order k.
+// se(v) of H.264 / H.265 is the code of order 0.
+//
+// Positive values are mapped to odd, others to even code numbers:
+// 0, 1, -1, 2, -2... are represented by the code numbers 0, 1, 2, 3, 4...
+func (w *Writer) WriteSE(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSE(w, v, k); err != nil {
+		err = wrapError("WriteSE", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUE tries to write u as an unsigned Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUE(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUE(u uint64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUE(u, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteSE tries to write v as a signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSE(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteSE(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSE(v, k)
+		w.checkTry()
+	}
+}
+
+// WriteUE writes u as an unsigned Exponential-Golomb code of order k,
+// and counts the number of bits written.
+// ue(v) of H.264 / H.265 is the code of order 0.
+//
+// Values whose code would have more than 31 leading zeros result in ErrOverflow.
+// k must not be greater than 63, else ErrInvalidParameter is returned.
+func (w *CountWriter) WriteUE(u uint64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeUE(w, u, k); err != nil {
+		err = wrapError("WriteUE", 0, off, err)
+	}
+	return
+}
+
+// WriteSE writes v as a signed Exponential-Golomb code of order k,
+// and counts the number of bits written.
+// se(v) of H.264 / H.265 is the code of order 0.
+//
+// Positive values are mapped to odd, others to even code numbers:
+// 0, 1, -1, 2, -2... are represented by the code numbers 0, 1, 2, 3, 4...
+func (w *CountWriter) WriteSE(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSE(w, v, k); err != nil {
+		err = wrapError("WriteSE", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUE tries to write u as an unsigned Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUE(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUE(u uint64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUE(u, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteSE tries to write v as a signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSE(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteSE(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSE(v, k)
+		w.checkTry()
+	}
+}
+
+// readUE reads an unsigned Exponential-Golomb code of order k from r.
+func readUE(r bitReader, k uint8) (u uint64, err error) {
+	if k > 63 {
+		return 0, ErrInvalidParameter
+	}
+
+	// Leading zeros, terminated by a 1 bit
+	z, err := r.ReadUnary(true, maxExpGolombZeros)
+	if err != nil {
+		return 0, err
+	}
+	zeros := uint8(z)
+
+	n := zeros + k // number of bits following the terminating 1 bit
+	if n > 63 {
+		return 0, ErrOverflow
+	}
+	if u, err = r.ReadBits(n); err != nil {
+		return 0, noEOF(err)
+	}
+	// The value is the terminating 1 bit followed by the n bits, minus 1<<k
+	return (1<<n | u) - 1<<k, nil
+}
+
+// readSE reads a signed Exponential-Golomb code of order k from r.
+func readSE(r bitReader, k uint8) (v int64, err error) {
+	u, err := readUE(r, k)
+	if err != nil {
+		return 0, err
+	}
+	if u&1 == 1 {
+		return int64(u>>1) + 1, nil
+	}
+	return -int64(u >> 1), nil
+}
+
+// writeUE writes u as an unsigned Exponential-Golomb code of order k to w.
+func writeUE(w bitWriter, u uint64, k uint8) (err error) {
+	if k > 63 {
+		return ErrInvalidParameter
+	}
+	x := u + 1<<k
+	if x < u {
+		return ErrOverflow // u + 1<<k overflows uint64
+	}
+
+	n := uint8(bits.Len64(x)) - 1 // number of bits following the leading 1 bit
+	zeros := n - k
+	if zeros > maxExpGolombZeros {
+		return ErrOverflow
+	}
+	if err = w.WriteBits(0, zeros); err != nil {
+		return
+	}
+	// Leading 1 bit separately, so codes are read back properly in both bit orders
+	if err = w.WriteBool(true); err != nil {
+		return
+	}
+	return w.WriteBits(x, n)
+}
+
+// writeSE writes v as a signed Exponential-Golomb code of order k to w.
+func writeSE(w bitWriter, v int64, k uint8) (err error) {
+	var u uint64
+	switch {
+	case v > 0:
+		u = uint64(v)<<1 - 1
+	case v == math.MinInt64:
+		return ErrOverflow // -v<<1 would overflow uint64
+	default:
+		u = uint64(-v) << 1
+	}
+	return writeUE(w, u, k)
+}
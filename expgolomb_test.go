@@ -0,0 +1,159 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestExpGolomb(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteUE(0, 0))  // 1
+	eq(nil, w.WriteUE(1, 0))  // 010
+	eq(nil, w.WriteUE(2, 0))  // 011
+	eq(nil, w.WriteUE(3, 0))  // 00100
+	eq(nil, w.WriteSE(0, 0))  // 1
+	eq(nil, w.WriteSE(1, 0))  // 010
+	eq(nil, w.WriteSE(-1, 0)) // 011
+	eq(nil, w.WriteSE(2, 0))  // 00100
+	eq(nil, w.WriteUE(0, 2))  // 100
+	eq(nil, w.WriteUE(4, 2))  // 01000
+	eq(nil, w.Close())
+	// 1010 0110 0100 1010 0110 0100 1000 1000
+	eq(true, bytes.Equal(b.Bytes(), []byte{0xa6, 0x4a, 0x64, 0x88}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	for _, u := range []uint64{0, 1, 2, 3} {
+		expEq(u)(r.ReadUE(0))
+	}
+	for _, v := range []int64{0, 1, -1, 2} {
+		expEq(v)(r.ReadSE(0))
+	}
+	expEq(uint64(0))(r.ReadUE(2))
+	expEq(uint64(4))(r.ReadUE(2))
+	_, err := r.ReadUE(0)
+	eq(io.EOF, err)
+
+	// Leading zeros read, the rest of the code is missing
+	r = NewReader(bytes.NewBuffer([]byte{0x01}))
+	_, err = r.ReadUE(0)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestExpGolombChain(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	values := []int64{0, 1, -1, math.MaxInt32, math.MinInt32 + 1}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Int63n(1<<20)-1<<19)
+	}
+
+	for k := uint8(0); k < 16; k++ {
+		newWriter, newReader := NewWriter, NewReader
+		if k >= 8 {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+		k := k % 8
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for _, v := range values {
+			eq(nil, w.WriteSE(v, k))
+			if v >= 0 {
+				eq(nil, w.WriteUE(uint64(v), k))
+			}
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, v := range values {
+			expEq(v)(r.ReadSE(k))
+			if v >= 0 {
+				expEq(uint64(v))(r.ReadUE(k))
+			}
+		}
+	}
+
+	// Big values need a big order
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteUE(math.MaxUint64-1<<40, 40))
+	eq(nil, w.WriteSE(math.MaxInt64-1<<40, 40))
+	eq(nil, w.WriteSE(-(math.MaxInt64-1<<40), 40))
+	eq(nil, w.Close())
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(math.MaxUint64 - 1<<40))(r.ReadUE(40))
+	expEq(int64(math.MaxInt64 - 1<<40))(r.ReadSE(40))
+	expEq(int64(-(math.MaxInt64 - 1<<40)))(r.ReadSE(40))
+}
+
+func TestExpGolombOverflow(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	w := NewWriter(&bytes.Buffer{})
+	eq(nil, w.WriteUE(1<<32-2, 0)) // 31 leading zeros
+	eq(true, errors.Is(w.WriteUE(1<<32-1, 0), ErrOverflow))
+	eq(true, errors.Is(w.WriteUE(math.MaxUint64, 1), ErrOverflow))
+	eq(true, errors.Is(w.WriteSE(math.MinInt64, 40), ErrOverflow))
+	eq(true, errors.Is(w.WriteSE(1<<31, 0), ErrOverflow))
+	w.TryWriteSE(-1<<31, 0)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+
+	// 32 leading zeros
+	r := NewReader(bytes.NewBuffer([]byte{0, 0, 0, 0, 0xff}))
+	_, err := r.ReadUE(0)
+	eq(true, errors.Is(err, ErrOverflow))
+
+	// 31 leading zeros and order 33
+	r = NewReader(bytes.NewBuffer([]byte{0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	_, err = r.ReadSE(33)
+	eq(true, errors.Is(err, ErrOverflow))
+
+	r = NewReader(bytes.NewBuffer([]byte{0, 0, 0, 0, 0}))
+	_ = r.TryReadSE(0)
+	eq(true, errors.Is(r.TryError, ErrOverflow))
+
+	// Order greater than 63
+	eq(true, errors.Is(w.WriteUE(0, 64), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteSE(0, 250), ErrInvalidParameter))
+	r = NewReader(bytes.NewBuffer([]byte{0x02, 0xff, 0xff, 0xff})) // 6 leading zeros
+	_, err = r.ReadUE(250)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	_, err = r.ReadSE(64)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	expEq(uint64(189))(r.ReadUE(1)) // Nothing has been consumed
+}
+
+func TestExpGolombCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteUE(3, 0))
+	eq(int64(5), w.BitsCount)
+	eq(nil, w.WriteSE(-1, 0))
+	eq(int64(8), w.BitsCount)
+	w.TryWriteUE(4, 2)
+	w.TryWriteSE(0, 1)
+	eq(nil, w.TryError)
+	eq(int64(15), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(3))(r.ReadUE(0))
+	eq(int64(5), r.BitsCount)
+	expEq(int64(-1))(r.ReadSE(0))
+	eq(int64(8), r.BitsCount)
+	eq(uint64(4), r.TryReadUE(2))
+	eq(int64(0), r.TryReadSE(1))
+	eq(nil, r.TryError)
+	eq(int64(15), r.BitsCount)
+}
@@ -0,0 +1,485 @@
+/*
+
+IEEE 754 floating point numbers.
+
+*/
+
+package bitio
+
+import "math"
+
+// Floating point numbers are read and written at the current bit position
+// as their bit representation, as if by ReadBits() and WriteBits().
+//
+// Half precision (binary16) and bfloat16 numbers are returned and taken as float32
+// (which can represent all their values exactly). When writing them, values
+// are rounded to nearest, ties to even. NaN payloads are preserved (as much as
+// the target format allows), so NaNs survive a read-write round trip bit-exactly.
+
+// ReadFloat32 reads a 32-bit IEEE 754 single precision floating point number.
+func (r *Reader) ReadFloat32() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readFloat32(r); err != nil {
+		err = wrapError("ReadFloat32", 32, off, err)
+	}
+	return
+}
+
+// ReadFloat64 reads a 64-bit IEEE 754 double precision floating point number.
+func (r *Reader) ReadFloat64() (f float64, err error) {
+	off := r.bitPos()
+	if f, err = readFloat64(r); err != nil {
+		err = wrapError("ReadFloat64", 64, off, err)
+	}
+	return
+}
+
+// ReadFloat16 reads a 16-bit IEEE 754 half precision (binary16) floating point number.
+func (r *Reader) ReadFloat16() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readFloat16(r); err != nil {
+		err = wrapError("ReadFloat16", 16, off, err)
+	}
+	return
+}
+
+// ReadBFloat16 reads a 16-bit bfloat16 (brain floating point) number.
+func (r *Reader) ReadBFloat16() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readBFloat16(r); err != nil {
+		err = wrapError("ReadBFloat16", 16, off, err)
+	}
+	return
+}
+
+// TryReadFloat32 tries to read a single precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat32(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadFloat32() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat32()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadFloat64 tries to read a double precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat64(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadFloat64() (f float64) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat64()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadFloat16 tries to read a half precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadFloat16() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat16()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadBFloat16 tries to read a bfloat16 number.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBFloat16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadBFloat16() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadBFloat16()
+		r.checkTry()
+	}
+	return
+}
+
+// ReadFloat32 reads a 32-bit IEEE 754 single precision floating point number,
+// and counts the number of bits read.
+func (r *CountReader) ReadFloat32() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readFloat32(r); err != nil {
+		err = wrapError("ReadFloat32", 32, off, err)
+	}
+	return
+}
+
+// ReadFloat64 reads a 64-bit IEEE 754 double precision floating point number,
+// and counts the number of bits read.
+func (r *CountReader) ReadFloat64() (f float64, err error) {
+	off := r.bitPos()
+	if f, err = readFloat64(r); err != nil {
+		err = wrapError("ReadFloat64", 64, off, err)
+	}
+	return
+}
+
+// ReadFloat16 reads a 16-bit IEEE 754 half precision (binary16) floating point number,
+// and counts the number of bits read.
+func (r *CountReader) ReadFloat16() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readFloat16(r); err != nil {
+		err = wrapError("ReadFloat16", 16, off, err)
+	}
+	return
+}
+
+// ReadBFloat16 reads a 16-bit bfloat16 (brain floating point) number,
+// and counts the number of bits read.
+func (r *CountReader) ReadBFloat16() (f float32, err error) {
+	off := r.bitPos()
+	if f, err = readBFloat16(r); err != nil {
+		err = wrapError("ReadBFloat16", 16, off, err)
+	}
+	return
+}
+
+// TryReadFloat32 tries to read a single precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat32(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadFloat32() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat32()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadFloat64 tries to read a double precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat64(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadFloat64() (f float64) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat64()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadFloat16 tries to read a half precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadFloat16() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadFloat16()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadBFloat16 tries to read a bfloat16 number.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBFloat16(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadBFloat16() (f float32) {
+	if r.TryError == nil {
+		f, r.TryError = r.ReadBFloat16()
+		r.checkTry()
+	}
+	return
+}
+
+// WriteFloat32 writes f as a 32-bit IEEE 754 single precision floating point number.
+func (w *Writer) WriteFloat32(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeFloat32(w, f); err != nil {
+		err = wrapError("WriteFloat32", 32, off, err)
+	}
+	return
+}
+
+// WriteFloat64 writes f as a 64-bit IEEE 754 double precision floating point number.
+func (w *Writer) WriteFloat64(f float64) (err error) {
+	off := w.bitPos()
+	if err = writeFloat64(w, f); err != nil {
+		err = wrapError("WriteFloat64", 64, off, err)
+	}
+	return
+}
+
+// WriteFloat16 writes f as a 16-bit IEEE 754 half precision (binary16) floating point number.
+func (w *Writer) WriteFloat16(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeFloat16(w, f); err != nil {
+		err = wrapError("WriteFloat16", 16, off, err)
+	}
+	return
+}
+
+// WriteBFloat16 writes f as a 16-bit bfloat16 (brain floating point) number.
+func (w *Writer) WriteBFloat16(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeBFloat16(w, f); err != nil {
+		err = wrapError("WriteBFloat16", 16, off, err)
+	}
+	return
+}
+
+// TryWriteFloat32 tries to write f as a single precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat32(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteFloat32(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat32(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteFloat64 tries to write f as a double precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat64(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteFloat64(f float64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat64(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteFloat16 tries to write f as a half precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat16(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteFloat16(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat16(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteBFloat16 tries to write f as a bfloat16 number.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBFloat16(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteBFloat16(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBFloat16(f)
+		w.checkTry()
+	}
+}
+
+// WriteFloat32 writes f as a 32-bit IEEE 754 single precision floating point number,
+// and counts the number of bits written.
+func (w *CountWriter) WriteFloat32(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeFloat32(w, f); err != nil {
+		err = wrapError("WriteFloat32", 32, off, err)
+	}
+	return
+}
+
+// WriteFloat64 writes f as a 64-bit IEEE 754 double precision floating point number,
+// and counts the number of bits written.
+func (w *CountWriter) WriteFloat64(f float64) (err error) {
+	off := w.bitPos()
+	if err = writeFloat64(w, f); err != nil {
+		err = wrapError("WriteFloat64", 64, off, err)
+	}
+	return
+}
+
+// WriteFloat16 writes f as a 16-bit IEEE 754 half precision (binary16) floating point number,
+// and counts the number of bits written.
+func (w *CountWriter) WriteFloat16(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeFloat16(w, f); err != nil {
+		err = wrapError("WriteFloat16", 16, off, err)
+	}
+	return
+}
+
+// WriteBFloat16 writes f as a 16-bit bfloat16 (brain floating point) number,
+// and counts the number of bits written.
+func (w *CountWriter) WriteBFloat16(f float32) (err error) {
+	off := w.bitPos()
+	if err = writeBFloat16(w, f); err != nil {
+		err = wrapError("WriteBFloat16", 16, off, err)
+	}
+	return
+}
+
+// TryWriteFloat32 tries to write f as a single precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat32(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteFloat32(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat32(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteFloat64 tries to write f as a double precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat64(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteFloat64(f float64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat64(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteFloat16 tries to write f as a half precision float.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat16(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteFloat16(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat16(f)
+		w.checkTry()
+	}
+}
+
+// TryWriteBFloat16 tries to write f as a bfloat16 number.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBFloat16(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteBFloat16(f float32) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBFloat16(f)
+		w.checkTry()
+	}
+}
+
+// readFloat32 reads a single precision float from r.
+func readFloat32(r bitReader) (f float32, err error) {
+	u, err := r.ReadBits(32)
+	return math.Float32frombits(uint32(u)), err
+}
+
+// readFloat64 reads a double precision float from r.
+func readFloat64(r bitReader) (f float64, err error) {
+	u, err := r.ReadBits(64)
+	return math.Float64frombits(u), err
+}
+
+// readFloat16 reads a half precision float from r.
+func readFloat16(r bitReader) (f float32, err error) {
+	u, err := r.ReadBits(16)
+	return math.Float32frombits(float16ToFloat32Bits(uint16(u))), err
+}
+
+// readBFloat16 reads a bfloat16 number from r.
+func readBFloat16(r bitReader) (f float32, err error) {
+	u, err := r.ReadBits(16)
+	// bfloat16 is the upper half of a float32
+	return math.Float32frombits(uint32(u) << 16), err
+}
+
+// writeFloat32 writes f as a single precision float to w.
+func writeFloat32(w bitWriter, f float32) (err error) {
+	return w.WriteBits(uint64(math.Float32bits(f)), 32)
+}
+
+// writeFloat64 writes f as a double precision float to w.
+func writeFloat64(w bitWriter, f float64) (err error) {
+	return w.WriteBits(math.Float64bits(f), 64)
+}
+
+// writeFloat16 writes f as a half precision float to w.
+func writeFloat16(w bitWriter, f float32) (err error) {
+	return w.WriteBits(uint64(float32BitsToFloat16(math.Float32bits(f))), 16)
+}
+
+// writeBFloat16 writes f as a bfloat16 number to w.
+func writeBFloat16(w bitWriter, f float32) (err error) {
+	return w.WriteBits(uint64(float32BitsToBFloat16(math.Float32bits(f))), 16)
+}
+
+// float16ToFloat32Bits converts the half precision float h
+// to the bits of a single precision float. The conversion is exact.
+func float16ToFloat32Bits(h uint16) uint32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f: // Infinity or NaN (payload is kept)
+		return sign | 0x7f800000 | mant<<13
+	case exp != 0: // Normal number
+		return sign | (exp-15+127)<<23 | mant<<13
+	case mant == 0: // Zero
+		return sign
+	}
+
+	// Subnormal half, normal float32: normalize mantissa
+	exp = 1 - 15 + 127
+	for mant&0x400 == 0 {
+		mant <<= 1
+		exp--
+	}
+	return sign | exp<<23 | (mant&0x3ff)<<13
+}
+
+// float32BitsToFloat16 converts the single precision float of bits b
+// to a half precision float, rounding to nearest, ties to even.
+func float32BitsToFloat16(b uint32) uint16 {
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if exp == 0xff {
+		if mant == 0 { // Infinity
+			return sign | 0x7c00
+		}
+		// NaN: keep the highest bits of the payload, but it must not become infinity
+		m := uint16(mant >> 13)
+		if m == 0 {
+			m = 0x200
+		}
+		return sign | 0x7c00 | m
+	}
+
+	e := exp - 127 + 15 // exponent of the half
+	if e >= 0x1f {
+		return sign | 0x7c00 // Overflow to infinity
+	}
+
+	var shift uint // number of mantissa bits to drop
+	if e > 0 {
+		shift = 13
+	} else {
+		// Subnormal half (or zero): the implicit leading bit becomes explicit
+		if exp != 0 {
+			mant |= 0x800000
+		}
+		if 14-e > 24 {
+			return sign // Less than half of the smallest subnormal: rounds to zero
+		}
+		shift = uint(14 - e)
+		e = 0
+	}
+
+	m := mant >> shift
+	rem, half := mant&(1<<shift-1), uint32(1)<<(shift-1)
+	if rem > half || rem == half && m&1 == 1 {
+		m++ // A carry properly propagates into the exponent (up to infinity)
+	}
+	return sign | (uint16(e)<<10 + uint16(m))
+}
+
+// float32BitsToBFloat16 converts the single precision float of bits b
+// to a bfloat16 number, rounding to nearest, ties to even.
+func float32BitsToBFloat16(b uint32) uint16 {
+	if b&0x7f800000 == 0x7f800000 && b&0x7fffff != 0 {
+		// NaN: keep the highest bits of the payload, but it must not become infinity
+		h := uint16(b >> 16)
+		if h&0x7f == 0 {
+			h |= 0x40
+		}
+		return h
+	}
+	// A carry properly propagates into the exponent (up to infinity)
+	return uint16((b + 0x7fff + b>>16&1) >> 16)
+}
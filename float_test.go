@@ -0,0 +1,205 @@
+package bitio
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestFloat(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteBool(true))
+	eq(nil, w.WriteFloat32(1.5))     // 0x3fc00000
+	eq(nil, w.WriteFloat64(-2))      // 0xc000000000000000
+	eq(nil, w.WriteFloat16(-0.75))   // 0xba00
+	eq(nil, w.WriteBFloat16(3.1415)) // 0x4049
+	eq(nil, w.Close())
+	// Shifted by 1 bit
+	exp := []byte{
+		0x9f, 0xe0, 0x00, 0x00,
+		0x60, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x5d, 0x00,
+		0x20, 0x24, 0x80,
+	}
+	eq(true, bytes.Equal(b.Bytes(), exp))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(true)(r.ReadBool())
+	expEq(float32(1.5))(r.ReadFloat32())
+	expEq(float64(-2))(r.ReadFloat64())
+	expEq(float32(-0.75))(r.ReadFloat16())
+	expEq(float32(3.140625))(r.ReadBFloat16())
+}
+
+func TestFloatChain(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	var f32s []float32
+	var f64s []float64
+	for i := 0; i < 1000; i++ {
+		f32s = append(f32s, math.Float32frombits(rand.Uint32()))
+		f64s = append(f64s, math.Float64frombits(rand.Uint64()))
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for i := range f32s {
+			eq(nil, w.WriteBits(uint64(i), uint8(i%8)))
+			eq(nil, w.WriteFloat32(f32s[i]))
+			eq(nil, w.WriteFloat64(f64s[i]))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for i := range f32s {
+			_, err := r.ReadBits(uint8(i % 8))
+			eq(nil, err)
+			// Compare bits, as NaN != NaN
+			f32, err := r.ReadFloat32()
+			eq(nil, err)
+			eq(math.Float32bits(f32s[i]), math.Float32bits(f32))
+			f64, err := r.ReadFloat64()
+			eq(nil, err)
+			eq(math.Float64bits(f64s[i]), math.Float64bits(f64))
+		}
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	// All half and bfloat16 values (including NaN payloads) must survive a round trip
+	for h := 0; h < 1<<16; h++ {
+		eq(uint16(h), float32BitsToFloat16(float16ToFloat32Bits(uint16(h))))
+		eq(uint16(h), float32BitsToBFloat16(uint32(h)<<16))
+	}
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	for h := 0; h < 1<<16; h++ {
+		eq(nil, w.WriteBits(uint64(h), 16))
+		eq(nil, w.WriteBits(uint64(h), 16))
+	}
+	eq(nil, w.Close())
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	b2 := &bytes.Buffer{}
+	w = NewWriter(b2)
+	for h := 0; h < 1<<16; h++ {
+		f, err := r.ReadFloat16()
+		eq(nil, err)
+		eq(nil, w.WriteFloat16(f))
+		f, err = r.ReadBFloat16()
+		eq(nil, err)
+		eq(nil, w.WriteBFloat16(f))
+	}
+	eq(nil, w.Close())
+	eq(true, bytes.Equal(b.Bytes(), b2.Bytes()))
+}
+
+func TestFloat16Rounding(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	f16 := func(f float32) uint16 { return float32BitsToFloat16(math.Float32bits(f)) }
+	bf16 := func(f float32) uint16 { return float32BitsToBFloat16(math.Float32bits(f)) }
+
+	eq(uint16(0x3c00), f16(1))
+	eq(uint16(0x3c00), f16(1+1.0/(1<<11))) // Tie, rounds to even
+	eq(uint16(0x3c02), f16(1+3.0/(1<<11))) // Tie, rounds to even
+	eq(uint16(0x3c01), f16(1+1.0/(1<<11)+1.0/(1<<20)))
+	eq(uint16(0x7bff), f16(65519))
+	eq(uint16(0x7c00), f16(65520)) // Rounds up to infinity
+	eq(uint16(0xfc00), f16(-1e10))
+	eq(uint16(0x0001), f16(1.0/(1<<24))) // Smallest subnormal
+	eq(uint16(0x0000), f16(1.0/(1<<25))) // Tie, rounds to even (zero)
+	eq(uint16(0x0001), f16(1.5/(1<<25)))
+	eq(uint16(0x8000), f16(-1e-30))
+	eq(uint16(0x0400), f16(1023.5/(1<<24))) // Tie, rounds up to the smallest normal
+	eq(uint16(0x03fe), f16(1022.5/(1<<24))) // Tie, rounds to even
+	eq(uint16(0x0000), f16(math.Float32frombits(1)))
+
+	eq(uint16(0x3f80), bf16(1+1.0/(1<<8)))    // Tie, rounds to even
+	eq(uint16(0x3f82), bf16(1+3.0/(1<<8)))    // Tie, rounds to even
+	eq(uint16(0x7f80), bf16(math.MaxFloat32)) // Rounds up to infinity
+	eq(uint16(0xff80), bf16(float32(math.Inf(-1))))
+
+	// NaNs with payload only in the dropped bits must remain NaNs
+	eq(uint16(0x7e00), float32BitsToFloat16(0x7f800001))
+	eq(uint16(0xffc0), float32BitsToBFloat16(0xff800001))
+	eq(uint16(0x7c01), float32BitsToFloat16(0x7f802000))
+
+	// Results must be nearest representable values
+	for i := 0; i < 100000; i++ {
+		f := math.Float32frombits(rand.Uint32())
+		if f != f || math.Abs(float64(f)) >= 65504 {
+			continue
+		}
+		h := f16(f)
+		d := math.Abs(float64(f) - float64(math.Float32frombits(float16ToFloat32Bits(h))))
+		for _, n := range []uint16{h - 1, h + 1} {
+			if n&0x7c00 == 0x7c00 {
+				continue
+			}
+			dn := math.Abs(float64(f) - float64(math.Float32frombits(float16ToFloat32Bits(n))))
+			eq(true, d < dn || d == dn && h&1 == 0)
+		}
+	}
+}
+
+func TestFloatTryCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteFloat32(1))
+	eq(nil, w.WriteFloat64(2))
+	eq(nil, w.WriteFloat16(3))
+	eq(nil, w.WriteBFloat16(4))
+	eq(int64(128), w.BitsCount)
+	w.TryWriteFloat32(5)
+	w.TryWriteFloat64(6)
+	w.TryWriteFloat16(7)
+	w.TryWriteBFloat16(8)
+	eq(nil, w.TryError)
+	eq(int64(256), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(float32(1))(r.ReadFloat32())
+	expEq(float64(2))(r.ReadFloat64())
+	expEq(float32(3))(r.ReadFloat16())
+	expEq(float32(4))(r.ReadBFloat16())
+	eq(int64(128), r.BitsCount)
+	eq(float32(5), r.TryReadFloat32())
+	eq(float64(6), r.TryReadFloat64())
+	eq(float32(7), r.TryReadFloat16())
+	eq(float32(8), r.TryReadBFloat16())
+	eq(nil, r.TryError)
+	eq(int64(256), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(float32(1), rr.TryReadFloat32())
+	eq(float64(2), rr.TryReadFloat64())
+	eq(float32(3), rr.TryReadFloat16())
+	eq(float32(4), rr.TryReadBFloat16())
+	eq(nil, rr.TryError)
+
+	ww := NewWriter(&bytes.Buffer{})
+	ww.TryWriteFloat32(1)
+	ww.TryWriteFloat64(1)
+	ww.TryWriteFloat16(1)
+	ww.TryWriteBFloat16(1)
+	eq(nil, ww.TryError)
+}
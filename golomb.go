@@ -0,0 +1,460 @@
+/*
+
+Golomb and Golomb-Rice coding.
+
+*/
+
+package bitio
+
+import (
+	"math"
+	"math/bits"
+)
+
+// The quotient of Golomb and Rice codes is coded in unary as a run of 0 bits
+// terminated by a 1 bit (like in FLAC).
+// Signed values are mapped to unsigned ones using zigzag encoding (see ZigzagEncode()):
+// 0, -1, 1, -2, 2... are mapped to 0, 1, 2, 3, 4...
+
+// ReadRice reads a Golomb-Rice code with parameter k
+// (a Golomb code with divisor 2^k).
+func (r *Reader) ReadRice(k uint8) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readRice(r, k); err != nil {
+		err = wrapError("ReadRice", 0, off, err)
+	}
+	return
+}
+
+// ReadRiceSigned reads a zigzag mapped signed Golomb-Rice code with parameter k.
+func (r *Reader) ReadRiceSigned(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readRice(r, k)
+	return ZigzagDecode(u), wrapError("ReadRiceSigned", 0, off, err)
+}
+
+// ReadGolomb reads a Golomb code with divisor m.
+// m must be positive, else ErrInvalidParameter is returned.
+func (r *Reader) ReadGolomb(m uint64) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readGolomb(r, m); err != nil {
+		err = wrapError("ReadGolomb", 0, off, err)
+	}
+	return
+}
+
+// ReadGolombSigned reads a zigzag mapped signed Golomb code with divisor m.
+// m must be positive, else ErrInvalidParameter is returned.
+func (r *Reader) ReadGolombSigned(m uint64) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readGolomb(r, m)
+	return ZigzagDecode(u), wrapError("ReadGolombSigned", 0, off, err)
+}
+
+// TryReadRice tries to read a Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadRice(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadRice(k uint8) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadRice(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadRiceSigned tries to read a zigzag mapped signed Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadRiceSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadRiceSigned(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadRiceSigned(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadGolomb tries to read a Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadGolomb(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadGolomb(m uint64) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadGolomb(m)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadGolombSigned tries to read a zigzag mapped signed Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadGolombSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadGolombSigned(m uint64) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadGolombSigned(m)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadRice reads a Golomb-Rice code with parameter k
+// (a Golomb code with divisor 2^k), and counts the number of bits read.
+func (r *CountReader) ReadRice(k uint8) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readRice(r, k); err != nil {
+		err = wrapError("ReadRice", 0, off, err)
+	}
+	return
+}
+
+// ReadRiceSigned reads a zigzag mapped signed Golomb-Rice code with parameter k,
+// and counts the number of bits read.
+func (r *CountReader) ReadRiceSigned(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readRice(r, k)
+	return ZigzagDecode(u), wrapError("ReadRiceSigned", 0, off, err)
+}
+
+// ReadGolomb reads a Golomb code with divisor m, and counts the number of bits read.
+// m must be positive, else ErrInvalidParameter is returned.
+func (r *CountReader) ReadGolomb(m uint64) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readGolomb(r, m); err != nil {
+		err = wrapError("ReadGolomb", 0, off, err)
+	}
+	return
+}
+
+// ReadGolombSigned reads a zigzag mapped signed Golomb code with divisor m,
+// and counts the number of bits read.
+// m must be positive, else ErrInvalidParameter is returned.
+func (r *CountReader) ReadGolombSigned(m uint64) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readGolomb(r, m)
+	return ZigzagDecode(u), wrapError("ReadGolombSigned", 0, off, err)
+}
+
+// TryReadRice tries to read a Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadRice(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadRice(k uint8) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadRice(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadRiceSigned tries to read a zigzag mapped signed Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadRiceSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadRiceSigned(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadRiceSigned(k)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadGolomb tries to read a Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadGolomb(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadGolomb(m uint64) (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadGolomb(m)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadGolombSigned tries to read a zigzag mapped signed Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadGolombSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadGolombSigned(m uint64) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadGolombSigned(m)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteRice writes u as a Golomb-Rice code with parameter k
+// (a Golomb code with divisor 2^k).
+func (w *Writer) WriteRice(u uint64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeRice(w, u, k); err != nil {
+		err = wrapError("WriteRice", 0, off, err)
+	}
+	return
+}
+
+// WriteRiceSigned writes v as a zigzag mapped signed Golomb-Rice code with parameter k.
+func (w *Writer) WriteRiceSigned(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeRice(w, ZigzagEncode(v), k); err != nil {
+		err = wrapError("WriteRiceSigned", 0, off, err)
+	}
+	return
+}
+
+// WriteGolomb writes u as a Golomb code with divisor m.
+// m must be positive, else ErrInvalidParameter is returned.
+func (w *Writer) WriteGolomb(u, m uint64) (err error) {
+	off := w.bitPos()
+	if err = writeGolomb(w, u, m); err != nil {
+		err = wrapError("WriteGolomb", 0, off, err)
+	}
+	return
+}
+
+// WriteGolombSigned writes v as a zigzag mapped signed Golomb code with divisor m.
+// m must be positive, else ErrInvalidParameter is returned.
+func (w *Writer) WriteGolombSigned(v int64, m uint64) (err error) {
+	off := w.bitPos()
+	if err = writeGolomb(w, ZigzagEncode(v), m); err != nil {
+		err = wrapError("WriteGolombSigned", 0, off, err)
+	}
+	return
+}
+
+// TryWriteRice tries to write u as a Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteRice(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteRice(u uint64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteRice(u, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteRiceSigned tries to write v as a zigzag mapped signed Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteRiceSigned(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteRiceSigned(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteRiceSigned(v, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteGolomb tries to write u as a Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteGolomb(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteGolomb(u, m uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteGolomb(u, m)
+		w.checkTry()
+	}
+}
+
+// TryWriteGolombSigned tries to write v as a zigzag mapped signed Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteGolombSigned(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteGolombSigned(v int64, m uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteGolombSigned(v, m)
+		w.checkTry()
+	}
+}
+
+// WriteRice writes u as a Golomb-Rice code with parameter k
+// (a Golomb code with divisor 2^k), and counts the number of bits written.
+func (w *CountWriter) WriteRice(u uint64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeRice(w, u, k); err != nil {
+		err = wrapError("WriteRice", 0, off, err)
+	}
+	return
+}
+
+// WriteRiceSigned writes v as a zigzag mapped signed Golomb-Rice code with parameter k,
+// and counts the number of bits written.
+func (w *CountWriter) WriteRiceSigned(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeRice(w, ZigzagEncode(v), k); err != nil {
+		err = wrapError("WriteRiceSigned", 0, off, err)
+	}
+	return
+}
+
+// WriteGolomb writes u as a Golomb code with divisor m, and counts the number of bits written.
+// m must be positive, else ErrInvalidParameter is returned.
+func (w *CountWriter) WriteGolomb(u, m uint64) (err error) {
+	off := w.bitPos()
+	if err = writeGolomb(w, u, m); err != nil {
+		err = wrapError("WriteGolomb", 0, off, err)
+	}
+	return
+}
+
+// WriteGolombSigned writes v as a zigzag mapped signed Golomb code with divisor m,
+// and counts the number of bits written.
+// m must be positive, else ErrInvalidParameter is returned.
+func (w *CountWriter) WriteGolombSigned(v int64, m uint64) (err error) {
+	off := w.bitPos()
+	if err = writeGolomb(w, ZigzagEncode(v), m); err != nil {
+		err = wrapError("WriteGolombSigned", 0, off, err)
+	}
+	return
+}
+
+// TryWriteRice tries to write u as a Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteRice(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteRice(u uint64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteRice(u, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteRiceSigned tries to write v as a zigzag mapped signed Golomb-Rice code with parameter k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteRiceSigned(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteRiceSigned(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteRiceSigned(v, k)
+		w.checkTry()
+	}
+}
+
+// TryWriteGolomb tries to write u as a Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteGolomb(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteGolomb(u, m uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteGolomb(u, m)
+		w.checkTry()
+	}
+}
+
+// TryWriteGolombSigned tries to write v as a zigzag mapped signed Golomb code with divisor m.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteGolombSigned(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteGolombSigned(v int64, m uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteGolombSigned(v, m)
+		w.checkTry()
+	}
+}
+
+// maxQuotient returns the max quotient of a Golomb code with divisor m,
+// for which the coded value still fits into an uint64 (limited to maxInt).
+func maxQuotient(m uint64) int {
+	if q := uint64(math.MaxUint64) / m; q < uint64(maxInt) {
+		return int(q)
+	}
+	return maxInt
+}
+
+// readRice reads a Golomb-Rice code with parameter k from r.
+func readRice(r bitReader, k uint8) (u uint64, err error) {
+	if k > 63 {
+		return 0, ErrInvalidParameter
+	}
+	q, err := r.ReadUnary(true, maxQuotient(1<<k))
+	if err != nil {
+		return 0, err
+	}
+	if u, err = r.ReadBits(k); err != nil {
+		return 0, noEOF(err)
+	}
+	return uint64(q)<<k | u, nil
+}
+
+// readGolomb reads a Golomb code with divisor m from r.
+func readGolomb(r bitReader, m uint64) (u uint64, err error) {
+	if m == 0 {
+		return 0, ErrInvalidParameter
+	}
+	q, err := r.ReadUnary(true, maxQuotient(m))
+	if err != nil {
+		return 0, err
+	}
+
+	// Remainder is coded using truncated binary encoding:
+	// remainders less than cutoff are coded on b-1 bits, the rest on b bits.
+	if b := uint8(bits.Len64(m - 1)); b > 0 {
+		cutoff := 1<<b - m
+		if u, err = r.ReadBits(b - 1); err != nil {
+			return 0, noEOF(err)
+		}
+		if u >= cutoff {
+			bit, err := r.ReadBool()
+			if err != nil {
+				return 0, noEOF(err)
+			}
+			u <<= 1
+			if bit {
+				u |= 1
+			}
+			u -= cutoff
+		}
+	}
+
+	v := uint64(q)*m + u
+	if v < u {
+		return 0, ErrOverflow
+	}
+	return v, nil
+}
+
+// writeRice writes u as a Golomb-Rice code with parameter k to w.
+func writeRice(w bitWriter, u uint64, k uint8) (err error) {
+	if k > 63 {
+		return ErrInvalidParameter
+	}
+	q := u >> k
+	if q > uint64(maxInt) {
+		return ErrOverflow
+	}
+	if err = writeUnary(w, int(q), true); err != nil {
+		return
+	}
+	return w.WriteBits(u, k)
+}
+
+// writeGolomb writes u as a Golomb code with divisor m to w.
+func writeGolomb(w bitWriter, u, m uint64) (err error) {
+	if m == 0 {
+		return ErrInvalidParameter
+	}
+	q := u / m
+	if q > uint64(maxInt) {
+		return ErrOverflow
+	}
+	if err = writeUnary(w, int(q), true); err != nil {
+		return
+	}
+
+	// Remainder is coded using truncated binary encoding, see readGolomb()
+	b := uint8(bits.Len64(m - 1))
+	if b == 0 {
+		return nil // m is 1, remainder is always 0
+	}
+	cutoff := 1<<b - m
+	rem := u % m
+	if rem < cutoff {
+		return w.WriteBits(rem, b-1)
+	}
+	// b bits, the last one separately so codes are read back properly in both bit orders
+	rem += cutoff
+	if err = w.WriteBits(rem>>1, b-1); err != nil {
+		return
+	}
+	return w.WriteBool(rem&1 == 1)
+}
@@ -0,0 +1,176 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestGolomb(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteRice(5, 2))         // 01 01
+	eq(nil, w.WriteRiceSigned(-3, 1))  // 001 1
+	eq(nil, w.WriteGolomb(4, 3))       // 01 10
+	eq(nil, w.WriteGolomb(2, 3))       // 1 11
+	eq(nil, w.WriteGolombSigned(1, 3)) // 1 11
+	eq(nil, w.WriteGolomb(7, 1))       // 00000001
+	eq(nil, w.Close())
+	// 0101 0011 0110 1111 1100 0000 0100 0000
+	eq(true, bytes.Equal(b.Bytes(), []byte{0x53, 0x6f, 0xc0, 0x40}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(5))(r.ReadRice(2))
+	expEq(int64(-3))(r.ReadRiceSigned(1))
+	expEq(uint64(4))(r.ReadGolomb(3))
+	expEq(uint64(2))(r.ReadGolomb(3))
+	expEq(int64(1))(r.ReadGolombSigned(3))
+	expEq(uint64(7))(r.ReadGolomb(1))
+}
+
+func TestGolombChain(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	values := []int64{0, 1, -1, 300, -300, math.MaxInt64, math.MinInt64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Int63n(1<<16)-1<<15)
+	}
+	ms := []uint64{1, 2, 3, 5, 7, 10, 100, 1<<40 + 3, 1<<63 + 1, math.MaxUint64}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for _, v := range values {
+			k := uint8(ZigzagEncode(v) % 12)
+			if v == math.MaxInt64 || v == math.MinInt64 {
+				k = 60
+			}
+			eq(nil, w.WriteRiceSigned(v, k))
+			eq(nil, w.WriteRice(uint64(v), 63))
+			for _, m := range ms {
+				if ZigzagEncode(v)/m > 1<<16 {
+					continue // too long
+				}
+				eq(nil, w.WriteGolombSigned(v, m))
+			}
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, v := range values {
+			k := uint8(ZigzagEncode(v) % 12)
+			if v == math.MaxInt64 || v == math.MinInt64 {
+				k = 60
+			}
+			expEq(v)(r.ReadRiceSigned(k))
+			expEq(uint64(v))(r.ReadRice(63))
+			for _, m := range ms {
+				if ZigzagEncode(v)/m > 1<<16 {
+					continue // too long
+				}
+				expEq(v)(r.ReadGolombSigned(m))
+			}
+		}
+		// Zero padding bits of the last byte (if any) are a truncated code
+		_, err := r.ReadRice(0)
+		eq(true, err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF))
+	}
+}
+
+func TestGolombErrors(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	w := NewWriter(&bytes.Buffer{})
+	eq(true, errors.Is(w.WriteGolomb(1, 0), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteRice(1, 64), ErrInvalidParameter))
+	eq(true, errors.Is(w.WriteRice(math.MaxUint64, 0), ErrOverflow))
+	eq(true, errors.Is(w.WriteGolombSigned(math.MinInt64, 1), ErrOverflow))
+	w.TryWriteGolombSigned(1, 2)
+	w.TryWriteRiceSigned(1, 2)
+	eq(nil, w.TryError)
+	w.TryWriteGolomb(1, 0)
+	eq(true, errors.Is(w.TryError, ErrInvalidParameter))
+
+	r := NewReader(bytes.NewBuffer([]byte{0xff}))
+	_, err := r.ReadGolomb(0)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	_, err = r.ReadRiceSigned(64)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+
+	// Quotient 16 with k = 60 doesn't fit into uint64
+	r = NewReader(bytes.NewBuffer([]byte{0x00, 0x00, 0x80}))
+	_, err = r.ReadRice(60)
+	eq(true, errors.Is(err, ErrOverflow))
+
+	// q*m + remainder overflows
+	b := &bytes.Buffer{}
+	w = NewWriter(b)
+	eq(nil, w.WriteGolomb(math.MaxUint64, 1<<63))
+	eq(nil, w.Close())
+	data := b.Bytes()
+	data[len(data)-1] |= 0x7f // max remainder
+	r = NewReader(bytes.NewBuffer(data))
+	_, err = r.ReadGolomb(1<<63 + 1)
+	eq(true, errors.Is(err, ErrOverflow))
+
+	r = NewReader(bytes.NewBuffer([]byte{0x00}))
+	r.TryReadRiceSigned(2)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
+	r = NewReader(bytes.NewBuffer([]byte{0x00}))
+	r.TryReadGolombSigned(3)
+	eq(true, errors.Is(r.TryError, io.ErrUnexpectedEOF))
+	r = NewReader(bytes.NewBuffer([]byte{0x80}))
+	_, err = r.ReadRice(8) // Quotient read, remainder missing
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	r = NewReader(bytes.NewBuffer(nil))
+	_, err = r.ReadGolomb(3)
+	eq(io.EOF, err)
+}
+
+func TestGolombCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteRice(5, 2))
+	eq(int64(4), w.BitsCount)
+	eq(nil, w.WriteRiceSigned(-3, 1))
+	eq(int64(8), w.BitsCount)
+	eq(nil, w.WriteGolomb(4, 3))
+	eq(nil, w.WriteGolombSigned(1, 3))
+	eq(int64(15), w.BitsCount)
+	w.TryWriteRice(200, 0)
+	w.TryWriteRiceSigned(1, 0)
+	w.TryWriteGolomb(2, 3)
+	w.TryWriteGolombSigned(0, 3)
+	eq(nil, w.TryError)
+	eq(int64(15+201+3+3+2), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(5))(r.ReadRice(2))
+	eq(int64(4), r.BitsCount)
+	expEq(int64(-3))(r.ReadRiceSigned(1))
+	eq(int64(8), r.BitsCount)
+	expEq(uint64(4))(r.ReadGolomb(3))
+	expEq(int64(1))(r.ReadGolombSigned(3))
+	eq(int64(15), r.BitsCount)
+	eq(uint64(200), r.TryReadRice(0))
+	eq(int64(1), r.TryReadRiceSigned(0))
+	eq(uint64(2), r.TryReadGolomb(3))
+	eq(int64(0), r.TryReadGolombSigned(3))
+	eq(nil, r.TryError)
+	eq(int64(15+201+3+3+2), r.BitsCount)
+}
@@ -0,0 +1,303 @@
+/*
+
+Custom (mini) floating point formats.
+
+*/
+
+package bitio
+
+import "math"
+
+// FloatSpecials tells how infinities and NaNs are represented in a FloatFormat.
+type FloatSpecials uint8
+
+const (
+	// SpecialsIEEE is the IEEE 754 way: the max exponent is reserved,
+	// with zero mantissa it's infinity, else NaN.
+	SpecialsIEEE FloatSpecials = iota
+
+	// SpecialsNaN means there are no infinities, the max exponent with
+	// all 1 mantissa is NaN, all other values are finite (like in E4M3).
+	SpecialsNaN
+
+	// SpecialsNone means all values are finite.
+	SpecialsNone
+)
+
+// FloatFormat describes the layout of a binary floating point format.
+//
+// Values are laid out as the sign bit (if any), followed by ExpBits exponent bits
+// and MantBits mantissa bits, and read and written as if by ReadBits() and WriteBits().
+// The value of a normal number is 1.mantissa * 2^(exponent-Bias).
+//
+// ExpBits must be in the range 1..11 and MantBits must be at most 52,
+// so all values can be converted to float64 exactly.
+// Using invalid formats results in ErrInvalidParameter.
+type FloatFormat struct {
+	Signed   bool  // Tells if there is a sign bit
+	ExpBits  uint8 // Number of exponent bits
+	MantBits uint8 // Number of mantissa bits (excluding the implicit leading bit)
+	Bias     int   // Exponent bias
+
+	// Denormals tells if zero exponent means denormal (subnormal) numbers.
+	// If false, zero exponent represents zero only.
+	Denormals bool
+
+	// Specials tells how infinities and NaNs are represented.
+	Specials FloatSpecials
+}
+
+// Frequently used minifloat formats.
+var (
+	// FloatE4M3 is the 8-bit E4M3 format of OCP FP8 (also known as E4M3FN).
+	FloatE4M3 = FloatFormat{Signed: true, ExpBits: 4, MantBits: 3, Bias: 7, Denormals: true, Specials: SpecialsNaN}
+
+	// FloatE5M2 is the 8-bit E5M2 format of OCP FP8.
+	FloatE5M2 = FloatFormat{Signed: true, ExpBits: 5, MantBits: 2, Bias: 15, Denormals: true}
+
+	// FloatUnsigned11 is the 11-bit unsigned float of packed GPU formats (e.g. R11G11B10F).
+	FloatUnsigned11 = FloatFormat{ExpBits: 5, MantBits: 6, Bias: 15, Denormals: true}
+
+	// FloatUnsigned10 is the 10-bit unsigned float of packed GPU formats (e.g. R11G11B10F).
+	FloatUnsigned10 = FloatFormat{ExpBits: 5, MantBits: 5, Bias: 15, Denormals: true}
+)
+
+// Bits returns the total number of bits of the format.
+func (f FloatFormat) Bits() uint8 {
+	n := f.ExpBits + f.MantBits
+	if f.Signed {
+		n++
+	}
+	return n
+}
+
+// valid tells if f is a valid format.
+func (f FloatFormat) valid() bool {
+	return f.ExpBits >= 1 && f.ExpBits <= 11 && f.MantBits <= 52
+}
+
+// maxExp returns the max value of the exponent field.
+func (f FloatFormat) maxExp() uint64 {
+	return 1<<f.ExpBits - 1
+}
+
+// Decode returns the value of the floating point number whose bits are u.
+// Bits of u higher than Bits() are ignored.
+//
+// ErrInvalidParameter is returned if f is invalid.
+func (f FloatFormat) Decode(u uint64) (v float64, err error) {
+	if !f.valid() {
+		return 0, ErrInvalidParameter
+	}
+
+	exp, mant := u>>f.MantBits&f.maxExp(), u&(1<<f.MantBits-1)
+	switch {
+	case f.Specials == SpecialsIEEE && exp == f.maxExp():
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	case f.Specials == SpecialsNaN && exp == f.maxExp() && mant == 1<<f.MantBits-1:
+		v = math.NaN()
+	case exp == 0:
+		if f.Denormals {
+			v = math.Ldexp(float64(mant), 1-f.Bias-int(f.MantBits))
+		}
+	default:
+		v = math.Ldexp(float64(1<<f.MantBits|mant), int(exp)-f.Bias-int(f.MantBits))
+	}
+
+	if f.Signed && u>>(f.ExpBits+f.MantBits)&1 == 1 {
+		v = -v
+	}
+	return v, nil
+}
+
+// Encode returns the bits of v in format f, rounded to nearest, ties to even.
+//
+// Values too large to be represented are encoded as infinity if the format
+// has infinities, else ErrOverflow is returned. ErrOverflow is also returned
+// for NaN if the format has no NaN, and for negative values if the format
+// is unsigned. ErrInvalidParameter is returned if f is invalid.
+func (f FloatFormat) Encode(v float64) (u uint64, err error) {
+	if !f.valid() {
+		return 0, ErrInvalidParameter
+	}
+
+	var sign uint64
+	if math.Signbit(v) && v == v {
+		if !f.Signed && v != 0 {
+			return 0, ErrOverflow
+		}
+		if f.Signed {
+			sign = 1 << (f.ExpBits + f.MantBits)
+		}
+		v = -v
+	}
+
+	// Encoding of infinity and NaN, and the max finite value
+	var inf, nan, max uint64
+	hasInf, hasNaN := false, f.Specials != SpecialsNone
+	switch f.Specials {
+	case SpecialsIEEE:
+		inf, hasInf = f.maxExp()<<f.MantBits, true
+		nan, hasNaN = inf|1<<f.MantBits>>1, f.MantBits > 0
+		max = inf - 1
+	case SpecialsNaN:
+		nan = 1<<(f.ExpBits+f.MantBits) - 1
+		max = nan - 1
+	default:
+		max = 1<<(f.ExpBits+f.MantBits) - 1
+	}
+
+	switch {
+	case v != v:
+		if !hasNaN {
+			return 0, ErrOverflow
+		}
+		return nan, nil // Sign of NaN is not kept
+	case v == 0:
+		return sign, nil
+	}
+
+	if !math.IsInf(v, 0) {
+		_, e := math.Frexp(v)
+		exp := e - 1 + f.Bias // biased exponent, if v is normal in f
+		switch {
+		case exp > int(f.maxExp()):
+			// Too large, handled below
+		case exp >= 1:
+			// Normal: 1.mantissa, rounding may carry into the exponent
+			m := uint64(math.RoundToEven(math.Ldexp(v, int(f.MantBits)-(exp-f.Bias))))
+			u = uint64(exp)<<f.MantBits + m - 1<<f.MantBits
+		case f.Denormals:
+			// Denormal, rounding may give the smallest normal
+			u = uint64(math.RoundToEven(math.Ldexp(v, int(f.MantBits)-(1-f.Bias))))
+		default:
+			// Zero or the smallest normal
+			if v > math.Ldexp(1, -f.Bias) {
+				u = 1 << f.MantBits
+			}
+		}
+		if exp <= int(f.maxExp()) && u <= max {
+			return sign | u, nil
+		}
+	}
+
+	if !hasInf {
+		return 0, ErrOverflow
+	}
+	return sign | inf, nil
+}
+
+// ReadFloat reads a floating point number of format f.
+//
+// ErrInvalidParameter is returned if f is invalid.
+func (r *Reader) ReadFloat(f FloatFormat) (v float64, err error) {
+	off := r.bitPos()
+	if v, err = readFloat(r, f); err != nil {
+		err = wrapError("ReadFloat", int64(f.Bits()), off, err)
+	}
+	return
+}
+
+// TryReadFloat tries to read a floating point number of format f.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadFloat(f FloatFormat) (v float64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadFloat(f)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadFloat reads a floating point number of format f,
+// and counts the number of bits read.
+//
+// ErrInvalidParameter is returned if f is invalid.
+func (r *CountReader) ReadFloat(f FloatFormat) (v float64, err error) {
+	off := r.bitPos()
+	if v, err = readFloat(r, f); err != nil {
+		err = wrapError("ReadFloat", int64(f.Bits()), off, err)
+	}
+	return
+}
+
+// TryReadFloat tries to read a floating point number of format f.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadFloat(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadFloat(f FloatFormat) (v float64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadFloat(f)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteFloat writes v as a floating point number of format f.
+// v is rounded to nearest, ties to even, see FloatFormat.Encode() for errors.
+func (w *Writer) WriteFloat(v float64, f FloatFormat) (err error) {
+	off := w.bitPos()
+	if err = writeFloat(w, v, f); err != nil {
+		err = wrapError("WriteFloat", int64(f.Bits()), off, err)
+	}
+	return
+}
+
+// TryWriteFloat tries to write v as a floating point number of format f.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteFloat(v float64, f FloatFormat) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat(v, f)
+		w.checkTry()
+	}
+}
+
+// WriteFloat writes v as a floating point number of format f,
+// and counts the number of bits written.
+// v is rounded to nearest, ties to even, see FloatFormat.Encode() for errors.
+func (w *CountWriter) WriteFloat(v float64, f FloatFormat) (err error) {
+	off := w.bitPos()
+	if err = writeFloat(w, v, f); err != nil {
+		err = wrapError("WriteFloat", int64(f.Bits()), off, err)
+	}
+	return
+}
+
+// TryWriteFloat tries to write v as a floating point number of format f.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteFloat(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteFloat(v float64, f FloatFormat) {
+	if w.TryError == nil {
+		w.TryError = w.WriteFloat(v, f)
+		w.checkTry()
+	}
+}
+
+// readFloat reads a floating point number of format f from r.
+func readFloat(r bitReader, f FloatFormat) (v float64, err error) {
+	if !f.valid() {
+		return 0, ErrInvalidParameter
+	}
+	u, err := r.ReadBits(f.Bits())
+	if err != nil {
+		return 0, err
+	}
+	return f.Decode(u)
+}
+
+// writeFloat writes v as a floating point number of format f to w.
+func writeFloat(w bitWriter, v float64, f FloatFormat) (err error) {
+	u, err := f.Encode(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteBits(u, f.Bits())
+}
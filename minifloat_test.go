@@ -0,0 +1,211 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestFloatFormat(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	cases := []struct {
+		f FloatFormat
+		v float64
+		u uint64
+	}{
+		{FloatE4M3, 1, 0x38},
+		{FloatE4M3, -2.5, 0xc2},
+		{FloatE4M3, 448, 0x7e},
+		{FloatE4M3, math.Ldexp(1, -9), 0x01},
+		{FloatE4M3, math.Ldexp(7, -9), 0x07},
+		{FloatE4M3, math.Ldexp(1, -6), 0x08},
+		{FloatE5M2, 1, 0x3c},
+		{FloatE5M2, 57344, 0x7b},
+		{FloatE5M2, math.Inf(-1), 0xfc},
+		{FloatE5M2, math.Ldexp(1, -16), 0x01},
+		{FloatUnsigned11, 1, 0x3c0},
+		{FloatUnsigned11, 65024, 0x7bf},
+		{FloatUnsigned11, math.Inf(1), 0x7c0},
+		{FloatUnsigned10, 1, 0x1e0},
+		{FloatUnsigned10, 64512, 0x3df},
+		{FloatUnsigned10, math.Ldexp(1, -19), 0x01},
+	}
+	for _, c := range cases {
+		u, err := c.f.Encode(c.v)
+		eq(nil, err)
+		eq(c.u, u)
+		v, err := c.f.Decode(c.u)
+		eq(nil, err)
+		eq(c.v, v)
+	}
+
+	// Rounding
+	encode := func(f FloatFormat, v float64) uint64 {
+		u, err := f.Encode(v)
+		eq(nil, err)
+		return u
+	}
+	eq(uint64(0x38), encode(FloatE4M3, 1.0625)) // Tie, rounds to even
+	eq(uint64(0x3a), encode(FloatE4M3, 1.1875)) // Tie, rounds to even
+	eq(uint64(0x39), encode(FloatE4M3, 1.07))
+	eq(uint64(0x7e), encode(FloatE4M3, 463))                  // Rounds down to max
+	eq(uint64(0x00), encode(FloatE4M3, math.Ldexp(1, -10)))   // Tie, rounds to even (zero)
+	eq(uint64(0x08), encode(FloatE4M3, math.Ldexp(15, -10)))  // Rounds up to the smallest normal
+	eq(uint64(0x80), encode(FloatE4M3, math.Copysign(0, -1))) // Negative zero
+	eq(uint64(0x7c), encode(FloatE5M2, 61440))                // Tie, rounds to infinity
+	eq(uint64(0x7b), encode(FloatE5M2, 61439))
+	eq(uint64(0x7f), encode(FloatE4M3, math.NaN()))
+	eq(uint64(0x7e), encode(FloatE5M2, math.NaN()))
+	eq(uint64(0), encode(FloatUnsigned11, math.Copysign(0, -1)))
+	eq(uint64(0x7c0), encode(FloatUnsigned11, 1e10))
+
+	// Without denormals
+	f := FloatFormat{Signed: true, ExpBits: 3, MantBits: 2, Bias: 3, Specials: SpecialsNone}
+	eq(uint64(0x04), encode(f, 0.25))
+	eq(uint64(0x04), encode(f, 0.13))
+	eq(uint64(0x00), encode(f, 0.125)) // Tie, rounds to even (zero)
+	eq(uint64(0x1f), encode(f, 28))    // Max
+	v, err := f.Decode(0x03)
+	eq(float64(0), v)
+	eq(nil, err)
+
+	// Errors
+	for _, c := range []struct {
+		f FloatFormat
+		v float64
+	}{
+		{FloatE4M3, 480}, {FloatE4M3, math.Inf(1)}, {FloatUnsigned10, -1},
+		{f, math.NaN()}, {f, 31}, {FloatFormat{ExpBits: 2, MantBits: 0}, math.NaN()},
+	} {
+		_, err := c.f.Encode(c.v)
+		eq(true, errors.Is(err, ErrOverflow))
+	}
+	for _, f := range []FloatFormat{{}, {ExpBits: 12}, {ExpBits: 8, MantBits: 53}, {MantBits: 4}} {
+		_, err := f.Encode(1)
+		eq(true, errors.Is(err, ErrInvalidParameter))
+		_, err = f.Decode(1)
+		eq(true, errors.Is(err, ErrInvalidParameter))
+	}
+}
+
+func TestFloatFormatRoundTrip(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	formats := []FloatFormat{
+		FloatE4M3, FloatE5M2, FloatUnsigned11, FloatUnsigned10,
+		{Signed: true, ExpBits: 3, MantBits: 2, Bias: 3, Specials: SpecialsNone},
+		{Signed: true, ExpBits: 5, MantBits: 10, Bias: 15, Denormals: true},
+	}
+	for _, f := range formats {
+		for u := uint64(0); u < 1<<f.Bits(); u++ {
+			v, err := f.Decode(u)
+			eq(nil, err)
+			u2, err := f.Encode(v)
+			eq(nil, err)
+			if v != v {
+				v2, _ := f.Decode(u2)
+				eq(true, v2 != v2)
+				continue
+			}
+			if v == 0 && !f.Denormals {
+				eq(u&(1<<f.Bits()>>1), u2) // Zero mantissa
+				continue
+			}
+			eq(u, u2)
+		}
+	}
+
+	// Half precision must be identical to the dedicated conversion
+	f16 := formats[len(formats)-1]
+	for i := 0; i < 100000; i++ {
+		f := math.Float32frombits(rand.Uint32())
+		if f != f {
+			continue
+		}
+		u, err := f16.Encode(float64(f))
+		eq(nil, err)
+		eq(uint64(float32BitsToFloat16(math.Float32bits(f))), u)
+	}
+
+	// float64 itself
+	f64 := FloatFormat{ExpBits: 11, MantBits: 52, Bias: 1023, Denormals: true}
+	for i := 0; i < 100000; i++ {
+		u := rand.Uint64() >> 1
+		if u>>52 == 0x7ff {
+			continue
+		}
+		v, err := f64.Decode(u)
+		eq(nil, err)
+		eq(math.Float64frombits(u), v)
+		u2, err := f64.Encode(v)
+		eq(nil, err)
+		eq(u, u2)
+	}
+}
+
+func TestFloatFormatReadWrite(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		eq(nil, w.WriteFloat(-2.5, FloatE4M3))
+		eq(nil, w.WriteFloat(57344, FloatE5M2))
+		eq(nil, w.WriteFloat(65024, FloatUnsigned11))
+		eq(nil, w.WriteFloat(0.5, FloatUnsigned10))
+		eq(true, errors.Is(w.WriteFloat(-1, FloatUnsigned10), ErrOverflow))
+		eq(true, errors.Is(w.WriteFloat(1, FloatFormat{}), ErrInvalidParameter))
+		eq(nil, w.Close())
+		eq(37, b.Len()*8-3) // 8+8+11+10 bits, 3 bits padding
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		expEq(-2.5)(r.ReadFloat(FloatE4M3))
+		expEq(float64(57344))(r.ReadFloat(FloatE5M2))
+		expEq(float64(65024))(r.ReadFloat(FloatUnsigned11))
+		expEq(0.5)(r.ReadFloat(FloatUnsigned10))
+		_, err := r.ReadFloat(FloatFormat{})
+		eq(true, errors.Is(err, ErrInvalidParameter))
+		_, err = r.ReadFloat(FloatE4M3)
+		eq(true, err != nil)
+	}
+}
+
+func TestFloatFormatTryCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteFloat(1, FloatE4M3))
+	w.TryWriteFloat(2, FloatUnsigned11)
+	eq(nil, w.TryError)
+	eq(int64(19), w.BitsCount)
+	w.TryWriteFloat(-2, FloatUnsigned11)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	eq(int64(19), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(float64(1))(r.ReadFloat(FloatE4M3))
+	eq(float64(2), r.TryReadFloat(FloatUnsigned11))
+	eq(nil, r.TryError)
+	eq(int64(19), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(float64(1), rr.TryReadFloat(FloatE4M3))
+	eq(float64(2), rr.TryReadFloat(FloatUnsigned11))
+	eq(nil, rr.TryError)
+
+	ww := NewWriter(&bytes.Buffer{})
+	ww.TryWriteFloat(1, FloatE5M2)
+	eq(nil, ww.TryError)
+}
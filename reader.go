@@ -7,15 +7,17 @@ Reader implementation.
 package bitio
 
 import (
-	"bufio"
+	"encoding/binary"
 	"io"
+	"io/ioutil"
 )
 
-// An io.Reader and io.ByteReader at the same time.
-type readerAndByteReader interface {
-	io.Reader
-	io.ByteReader
-}
+// readerBufSize is the size of the internal input buffer of Reader.
+const readerBufSize = 4096
+
+// maxConsecutiveEmptyReads is the max number of attempts to read data
+// from the input if it returns no data and no error.
+const maxConsecutiveEmptyReads = 100
 
 // Reader is the bit reader implementation.
 //
@@ -23,21 +25,139 @@ type readerAndByteReader interface {
 //
 // For convenience, it also implements io.Reader and io.ByteReader.
 type Reader struct {
-	in    readerAndByteReader
-	cache byte // unread bits are stored here
-	bits  byte // number of unread bits in cache
+	in     io.Reader
+	seeker io.Seeker // the source if it implements io.Seeker, used for skipping
+	err    error     // error of the last read from in, reported when buffered data runs out
+	nread  int64     // number of bytes read from in (or skipped by seeking)
+
+	buf  []byte // input buffer, unprocessed bytes are buf[r:w]
+	r, w int
+
+	// acc holds the unread bits: in highest-bits-first order in its highest bits,
+	// in least-significant-bit-first order in its lowest bits; other bits are zero.
+	acc  uint64
+	bits uint8 // number of unread bits in acc
+	lsb  bool  // tells if least-significant-bit-first order is used
 
 	// TryError holds the first error occurred in TryXXX() methods.
 	TryError error
+
+	// TryPanic tells if TryXXX() methods panic when they store an error in TryError,
+	// so processing can be aborted early. Use Catch() to recover from the panic.
+	TryPanic bool
 }
 
 // NewReader returns a new Reader using the specified io.Reader as the input (source).
+//
+// Reader buffers its input, so it may read more data from in than it returns.
+//
+// The returned Reader uses highest-bits-first order, see NewReaderLSB()
+// for least-significant-bit-first order.
 func NewReader(in io.Reader) *Reader {
-	bin, ok := in.(readerAndByteReader)
-	if !ok {
-		bin = bufio.NewReader(in)
+	r := &Reader{in: in, buf: make([]byte, readerBufSize)}
+	r.seeker, _ = in.(io.Seeker)
+	return r
+}
+
+// NewReaderLSB returns a new Reader using the specified io.Reader as the input (source),
+// which uses least-significant-bit-first order.
+func NewReaderLSB(in io.Reader) *Reader {
+	r := NewReader(in)
+	r.lsb = true
+	return r
+}
+
+// readErr returns the error of the last read from the input, and clears it.
+func (r *Reader) readErr() (err error) {
+	err, r.err = r.err, nil
+	return
+}
+
+// readMore reads a new chunk of data from the input into buf.
+// Must only be called if buf has free space after sliding its unprocessed bytes.
+func (r *Reader) readMore() {
+	// Slide unprocessed bytes to the beginning
+	if r.r > 0 {
+		r.w = copy(r.buf, r.buf[r.r:r.w])
+		r.r = 0
+	}
+
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := r.in.Read(r.buf[r.w:])
+		r.w += n
+		r.nread += int64(n)
+		if err != nil {
+			r.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
+	}
+	r.err = io.ErrNoProgress
+}
+
+// ensure makes sure at least n (n <= 64) bits are buffered in acc and buf,
+// reading more data from the input if needed.
+// Returns the read error if not enough data is available.
+func (r *Reader) ensure(n uint8) error {
+	for int(r.bits)+8*(r.w-r.r) < int(n) {
+		if r.err != nil {
+			return r.readErr()
+		}
+		r.readMore()
 	}
-	return &Reader{in: bin}
+	return nil
+}
+
+// bitPos returns the absolute bit offset of the next unread bit.
+// Buffering more data does not change it.
+func (r *Reader) bitPos() int64 {
+	return r.nread*8 - int64(r.w-r.r)*8 - int64(r.bits)
+}
+
+// fill moves as many whole bytes from buf to acc as fit.
+func (r *Reader) fill() {
+	if r.w-r.r >= 8 {
+		// Fast path: load 8 bytes at once, and keep the ones that fit
+		k := (64 - r.bits) >> 3 // number of bytes that fit
+		drop := 64 - 8*k        // number of bits of the word that don't fit
+		if r.lsb {
+			word := binary.LittleEndian.Uint64(r.buf[r.r:])
+			r.acc |= word << drop >> drop << r.bits
+		} else {
+			word := binary.BigEndian.Uint64(r.buf[r.r:])
+			r.acc |= word >> drop << drop >> r.bits
+		}
+		r.r += int(k)
+		r.bits += 8 * k
+		return
+	}
+
+	for r.bits <= 56 && r.r < r.w {
+		b := uint64(r.buf[r.r])
+		r.r++
+		if r.lsb {
+			r.acc |= b << r.bits
+		} else {
+			r.acc |= b << (56 - r.bits)
+		}
+		r.bits += 8
+	}
+}
+
+// take removes the next n (n <= r.bits) bits from acc, and returns them
+// as the lowest n bits of u.
+func (r *Reader) take(n uint8) (u uint64) {
+	if r.lsb {
+		u = r.acc & (1<<n - 1)
+		r.acc >>= n
+	} else {
+		u = r.acc >> (64 - n)
+		r.acc <<= n
+	}
+	r.bits -= n
+	return
 }
 
 // Read reads up to len(p) bytes (8 * len(p) bits) from the underlying reader.
@@ -47,116 +167,405 @@ func NewReader(in io.Reader) *Reader {
 // to a byte boundary (else all the individual bytes are assembled from multiple bytes).
 // Byte boundary can be ensured by calling Align().
 func (r *Reader) Read(p []byte) (n int, err error) {
-	// r.bits will be the same after reading 8 bits, so we don't need to update that.
-	if r.bits == 0 {
-		return r.in.Read(p)
+	if n, err = r.read(p); err != nil {
+		err = wrapError("Read", int64(len(p))*8, r.bitPos()-int64(n)*8, err)
 	}
+	return
+}
 
-	for ; n < len(p); n++ {
-		if p[n], err = r.readUnalignedByte(); err != nil {
-			return
+// read is the implementation of Read(), errors are not wrapped.
+func (r *Reader) read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if r.bits%8 != 0 {
+		// Unaligned: all bytes are assembled from 2 bytes
+		for ; n < len(p); n++ {
+			if p[n], err = r.readUnalignedByte(); err != nil {
+				return
+			}
 		}
+		return
 	}
 
-	return
+	// Aligned: first serve the whole bytes in acc, then the buffered bytes
+	for ; n < len(p) && r.bits > 0; n++ {
+		p[n] = byte(r.take(8))
+	}
+	if n == len(p) {
+		return
+	}
+	if r.r < r.w {
+		c := copy(p[n:], r.buf[r.r:r.w])
+		r.r += c
+		return n + c, nil
+	}
+	if n > 0 {
+		return
+	}
+
+	// Nothing buffered
+	if r.err != nil {
+		return 0, r.readErr()
+	}
+	if len(p) >= len(r.buf) {
+		// Large read, read directly into p to avoid copy.
+		// buf no longer holds the last bytes read from the source (see seekBits()).
+		r.r, r.w = 0, 0
+		n, err = r.in.Read(p)
+		r.nread += int64(n)
+		return
+	}
+	r.readMore()
+	if r.r == r.w {
+		return 0, r.readErr()
+	}
+	n = copy(p, r.buf[r.r:r.w])
+	r.r += n
+	return n, nil
 }
 
 // ReadBits reads n bits and returns them as the lowest n bits of u.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+//
+// If no bits are available, io.EOF is returned. If fewer than n bits are available,
+// they are consumed and returned in a *PartialReadError (wrapped in a *BitError).
 func (r *Reader) ReadBits(n uint8) (u uint64, err error) {
-	// Some optimization, frequent cases
-	if n < r.bits {
-		// cache has all needed bits, and there are some extra which will be left in cache
-		shift := r.bits - n
-		u = uint64(r.cache >> shift)
-		r.cache &= 1<<shift - 1
-		r.bits = shift
-		return
+	if n <= r.bits {
+		// Fast path: acc has all the needed bits
+		return r.take(n), nil
+	}
+	return r.readBits(n)
+}
+
+// readBits is the slow path of ReadBits() when acc has to be filled.
+func (r *Reader) readBits(n uint8) (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = r.pull(n); err != nil {
+		err = wrapError("ReadBits", int64(n), off, err)
 	}
+	return
+}
 
-	if n > r.bits {
-		// all cache bits needed, and it's not even enough so more will be read
-		if r.bits > 0 {
-			u = uint64(r.cache)
-			n -= r.bits
-		}
-		// Read whole bytes
-		for n >= 8 {
-			b, err2 := r.in.ReadByte()
-			if err2 != nil {
-				return 0, err2
-			}
-			u = u<<8 + uint64(b)
-			n -= 8
-		}
-		// Read last fraction, if any
-		if n > 0 {
-			if r.cache, err = r.in.ReadByte(); err != nil {
-				return 0, err
-			}
-			shift := 8 - n
-			u = u<<n + uint64(r.cache>>shift)
-			r.cache &= 1<<shift - 1
-			r.bits = shift
-		} else {
-			r.bits = 0
-		}
-		return u, nil
+// pull reads n bits filling acc as needed, errors are not wrapped.
+func (r *Reader) pull(n uint8) (u uint64, err error) {
+	if n > 64 {
+		return 0, ErrInvalidBitCount
+	}
+	if err = r.ensure(n); err != nil {
+		return 0, r.partialRead(err)
+	}
+	r.fill()
+	if n <= r.bits {
+		return r.take(n), nil
 	}
 
-	// cache has exactly as many as needed
-	r.bits = 0 // no need to clear cache, will be overwritten on next read
-	return uint64(r.cache), nil
+	// acc can't hold all the needed bits (n > 56): use all of acc, then refill it
+	k := r.bits
+	u = r.take(k)
+	r.fill()
+	if r.lsb {
+		return u | r.take(n-k)<<k, nil
+	}
+	return u<<(n-k) | r.take(n-k), nil
 }
 
-// ReadByte reads the next 8 bits and returns them as a byte.
-//
-// ReadByte implements io.ByteReader.
-func (r *Reader) ReadByte() (b byte, err error) {
-	// r.bits will be the same after reading 8 bits, so we don't need to update that.
-	if r.bits == 0 {
-		return r.in.ReadByte()
+// partialRead consumes the available bits (fewer than requested)
+// after ensure() failed with err, and returns the error to report.
+func (r *Reader) partialRead(err error) error {
+	if r.bits == 0 && r.r == r.w {
+		return err // Nothing available
 	}
-	return r.readUnalignedByte()
+
+	// Fewer than 64 bits are available, all fit into acc
+	r.fill()
+	bits := r.bits
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &PartialReadError{Bits: bits, Value: r.take(bits), Err: err}
 }
 
-// readUnalignedByte reads the next 8 bits which are (may be) unaligned and returns them as a byte.
+// readUnalignedByte reads the next 8 bits for byte-level reads.
+// Unlike ReadBits(), it does not consume the available bits if there are fewer than 8.
 func (r *Reader) readUnalignedByte() (b byte, err error) {
-	// r.bits will be the same after reading 8 bits, so we don't need to update that.
-	bits := r.bits
-	b = r.cache << (8 - bits)
-	r.cache, err = r.in.ReadByte()
-	if err != nil {
-		return 0, err
+	if err = r.ensure(8); err != nil {
+		if err == io.EOF && (r.bits > 0 || r.r < r.w) {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	u, _ := r.ReadBits(8) // Can't fail, 8 bits are buffered
+	return byte(u), nil
+}
+
+// ReadByte reads the next 8 bits and returns them as a byte.
+//
+// ReadByte implements io.ByteReader. If there are fewer than 8 bits available
+// (but not zero), io.ErrUnexpectedEOF is returned and no bits are consumed.
+func (r *Reader) ReadByte() (b byte, err error) {
+	if r.bits == 0 && r.r < r.w {
+		// Aligned, and there's a buffered byte
+		b = r.buf[r.r]
+		r.r++
+		return
+	}
+	if b, err = r.readUnalignedByte(); err != nil {
+		err = wrapError("ReadByte", 8, r.bitPos(), err)
 	}
-	b |= r.cache >> bits
-	r.cache &= 1<<bits - 1
 	return
 }
 
 // ReadBool reads the next bit, and returns true if it is 1.
 func (r *Reader) ReadBool() (b bool, err error) {
 	if r.bits == 0 {
-		r.cache, err = r.in.ReadByte()
+		if err = r.ensure(1); err != nil {
+			return false, wrapError("ReadBool", 1, r.bitPos(), err)
+		}
+		r.fill()
+	}
+
+	return r.take(1) == 1, nil
+}
+
+// PeekBits returns the next n bits as the lowest n bits of u
+// without advancing the bit stream. Bits may be peeked across byte boundaries.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+//
+// If fewer than n bits are available, the available bits are returned in the
+// highest positions of the n-bit value u (in the lowest positions in case of
+// least-significant-bit-first order) padded with zero bits, avail reports
+// their number, and err holds the error that prevented reading more
+// (io.EOF at the end of the input). Else avail is n and err is nil.
+func (r *Reader) PeekBits(n uint8) (u uint64, avail uint8, err error) {
+	if n > 64 {
+		return 0, 0, wrapError("PeekBits", int64(n), r.bitPos(), ErrInvalidBitCount)
+	}
+	avail = n
+	if err = r.ensure(n); err != nil {
+		avail = uint8(int(r.bits) + 8*(r.w-r.r)) // less than n
+		err = wrapError("PeekBits", int64(n), r.bitPos(), err)
+	}
+
+	// All avail bits are buffered, so reading them can't fail and won't slide buf:
+	// read them, then restore the state.
+	acc, bits, rpos := r.acc, r.bits, r.r
+	u, _ = r.ReadBits(avail)
+	r.acc, r.bits, r.r = acc, bits, rpos
+
+	if avail < n && !r.lsb {
+		u <<= n - avail
+	}
+	return
+}
+
+// PeekBool returns the next bit without advancing the bit stream,
+// true if it is 1.
+func (r *Reader) PeekBool() (b bool, err error) {
+	u, _, err := r.PeekBits(1)
+	return u == 1, err
+}
+
+// SkipBits skips (discards) the next n bits.
+//
+// Unlike ReadBits(), n is not limited to 64. If the source implements io.Seeker,
+// whole bytes are skipped by seeking instead of reading them. Note that in this
+// case skipping past the end of the input is not detected until the next read.
+func (r *Reader) SkipBits(n int64) (err error) {
+	_, err = r.skipBits(n)
+	return
+}
+
+// skipBits skips the next n bits, and returns the number of skipped bits.
+func (r *Reader) skipBits(n int64) (skipped int64, err error) {
+	if skipped, err = r.skip(n); err != nil {
+		err = wrapError("SkipBits", n, r.bitPos()-skipped, err)
+	}
+	return
+}
+
+// skip is the implementation of skipBits(), errors are not wrapped.
+func (r *Reader) skip(n int64) (skipped int64, err error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if n <= int64(r.bits) {
+		r.take(uint8(n))
+		return n, nil
+	}
+
+	// Use all of acc, after that the stream is byte aligned
+	skipped = int64(r.bits)
+	r.take(r.bits)
+
+	// Buffered bytes
+	if nbytes := (n - skipped) / 8; nbytes > 0 {
+		if buffered := int64(r.w - r.r); nbytes > buffered {
+			nbytes = buffered
+		}
+		r.r += int(nbytes)
+		skipped += nbytes * 8
+	}
+
+	// Whole bytes of the input
+	if nbytes := (n - skipped) / 8; nbytes > 0 {
+		var m int64
+		m, err = r.skipBytes(nbytes)
+		skipped += m * 8
 		if err != nil {
 			return
 		}
-		b = (r.cache & 0x80) != 0
-		r.cache, r.bits = r.cache&0x7f, 7
+	}
+
+	// Remaining fraction, if any
+	if rest := uint8(n - skipped); rest > 0 {
+		if _, err = r.pull(rest); err != nil {
+			if pe, ok := err.(*PartialReadError); ok {
+				skipped += int64(pe.Bits)
+			}
+			return
+		}
+		skipped += int64(rest)
+	}
+
+	return
+}
+
+// skipBytes skips the next n bytes of the input, seeking if the source
+// implements io.Seeker. Must only be called if buf is empty.
+// Returns the number of skipped bytes.
+func (r *Reader) skipBytes(n int64) (skipped int64, err error) {
+	if r.err != nil {
+		return 0, r.readErr()
+	}
+	// The buffered bytes are all consumed, and buf no longer holds
+	// the last bytes read from the source (see seekBits()).
+	r.r, r.w = 0, 0
+
+	if r.seeker == nil {
+		skipped, err = io.CopyN(ioutil.Discard, r.in, n)
+		r.nread += skipped
 		return
 	}
 
-	r.bits--
-	b = (r.cache & (1 << r.bits)) != 0
-	r.cache &= 1<<r.bits - 1
+	if _, err = r.seeker.Seek(n, io.SeekCurrent); err != nil {
+		return
+	}
+	r.nread += n
+	return n, nil
+}
+
+// BitPos returns the bit position of the next read: the number of bits read
+// (or skipped) since the Reader was created, unless SeekBits() changed it.
+func (r *Reader) BitPos() int64 {
+	return r.bitPos()
+}
+
+// SeekBits sets the bit position of the next read to offset, interpreted according to whence:
+// io.SeekStart means relative to the position of the source when the Reader was created,
+// io.SeekCurrent means relative to the current bit position (see BitPos()),
+// and io.SeekEnd means relative to the end of the source. The new bit position is returned,
+// or the current one in case of an error.
+//
+// Positions within the buffered data are reached without accessing the source.
+// Else the source must implement io.Seeker, except for seeking forward, which is done
+// by skipping bits (see SkipBits()). If it does not, ErrNotSeekable is returned.
+// If the new position is not at a byte boundary, the preceding bits of its byte are read,
+// so seeking to such a position past the end of the source fails.
+//
+// SeekBits does not change the BitsCount field of a CountReader.
+func (r *Reader) SeekBits(offset int64, whence int) (pos int64, err error) {
+	cur := r.bitPos()
+	if pos, err = r.seekBits(offset, whence); err != nil {
+		pos, err = r.bitPos(), wrapError("SeekBits", 0, cur, err)
+	}
 	return
 }
 
+// seekBits is the implementation of SeekBits(), errors are not wrapped.
+func (r *Reader) seekBits(offset int64, whence int) (pos int64, err error) {
+	var base int64 // position of the source when the Reader was created
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.bitPos() + offset
+	case io.SeekEnd:
+		if r.seeker == nil {
+			return 0, ErrNotSeekable
+		}
+		if base, err = r.seekerBase(); err != nil {
+			return
+		}
+		var end int64
+		if end, err = r.seeker.Seek(0, io.SeekEnd); err != nil {
+			return
+		}
+		// Restore the source position, so nothing changes if pos turns out to be invalid
+		if _, err = r.seeker.Seek(base+r.nread, io.SeekStart); err != nil {
+			return
+		}
+		pos = (end-base)*8 + offset
+	default:
+		return 0, ErrInvalidParameter
+	}
+	if pos < 0 {
+		return 0, ErrInvalidParameter
+	}
+
+	// Within the buffered data, buf[:w] holds the last w bytes read from the source
+	if start := r.nread - int64(r.w); pos >= start*8 && pos <= r.nread*8 {
+		r.r = int(pos/8 - start)
+		r.acc, r.bits = 0, 0
+		if frac := uint8(pos % 8); frac > 0 {
+			r.fill()
+			r.take(frac)
+		}
+		return pos, nil
+	}
+
+	if r.seeker == nil {
+		if cur := r.bitPos(); pos > cur {
+			_, err = r.skip(pos - cur)
+			return pos, err
+		}
+		return 0, ErrNotSeekable
+	}
+	if whence != io.SeekEnd { // Else base is already known
+		if base, err = r.seekerBase(); err != nil {
+			return
+		}
+	}
+
+	if _, err = r.seeker.Seek(base+pos/8, io.SeekStart); err != nil {
+		return
+	}
+	r.r, r.w, r.err = 0, 0, nil
+	r.acc, r.bits = 0, 0
+	r.nread = pos / 8
+	if frac := uint8(pos % 8); frac > 0 {
+		if _, err = r.pull(frac); err != nil {
+			return
+		}
+	}
+	return pos, nil
+}
+
+// seekerBase returns the position of the seekable source when the Reader was created.
+func (r *Reader) seekerBase() (base int64, err error) {
+	if base, err = r.seeker.Seek(0, io.SeekCurrent); err != nil {
+		return
+	}
+	return base - r.nread, nil
+}
+
 // Align aligns the bit stream to a byte boundary,
 // so next read will read/use data from the next byte.
 // Returns the number of unread / skipped bits.
 func (r *Reader) Align() (skipped uint8) {
-	skipped = r.bits
-	r.bits = 0 // no need to clear cache, will be overwritten on next read
+	skipped = r.bits % 8
+	r.take(skipped)
 	return
 }
 
@@ -167,6 +576,7 @@ func (r *Reader) Align() (skipped uint8) {
 func (r *Reader) TryRead(p []byte) (n int) {
 	if r.TryError == nil {
 		n, r.TryError = r.Read(p)
+		r.checkTry()
 	}
 	return
 }
@@ -178,6 +588,7 @@ func (r *Reader) TryRead(p []byte) (n int) {
 func (r *Reader) TryReadBits(n uint8) (u uint64) {
 	if r.TryError == nil {
 		u, r.TryError = r.ReadBits(n)
+		r.checkTry()
 	}
 	return
 }
@@ -189,6 +600,7 @@ func (r *Reader) TryReadBits(n uint8) (u uint64) {
 func (r *Reader) TryReadByte() (b byte) {
 	if r.TryError == nil {
 		b, r.TryError = r.ReadByte()
+		r.checkTry()
 	}
 	return
 }
@@ -200,6 +612,54 @@ func (r *Reader) TryReadByte() (b byte) {
 func (r *Reader) TryReadBool() (b bool) {
 	if r.TryError == nil {
 		b, r.TryError = r.ReadBool()
+		r.checkTry()
+	}
+	return
+}
+
+// TryPeekBits tries to return the next n bits without advancing the bit stream.
+//
+// If there was a previous TryError, it does nothing. Else it calls PeekBits(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryPeekBits(n uint8) (u uint64, avail uint8) {
+	if r.TryError == nil {
+		u, avail, r.TryError = r.PeekBits(n)
+		r.checkTry()
+	}
+	return
+}
+
+// TryPeekBool tries to return the next bit without advancing the bit stream.
+//
+// If there was a previous TryError, it does nothing. Else it calls PeekBool(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryPeekBool() (b bool) {
+	if r.TryError == nil {
+		b, r.TryError = r.PeekBool()
+		r.checkTry()
+	}
+	return
+}
+
+// TrySkipBits tries to skip the next n bits.
+//
+// If there was a previous TryError, it does nothing. Else it calls SkipBits(),
+// and stores the error in the TryError field.
+func (r *Reader) TrySkipBits(n int64) {
+	if r.TryError == nil {
+		r.TryError = r.SkipBits(n)
+		r.checkTry()
+	}
+}
+
+// TrySeekBits tries to set the bit position of the next read.
+//
+// If there was a previous TryError, it does nothing. Else it calls SeekBits(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TrySeekBits(offset int64, whence int) (pos int64) {
+	if r.TryError == nil {
+		pos, r.TryError = r.SeekBits(offset, whence)
+		r.checkTry()
 	}
 	return
 }
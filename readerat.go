@@ -0,0 +1,135 @@
+/*
+
+ReaderAt implementation.
+
+*/
+
+package bitio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ReaderAt is a random-access bit reader: it reads bits at arbitrary bit offsets
+// of an io.ReaderAt or a byte slice.
+//
+// ReaderAt is stateless, so it is safe for concurrent use by multiple goroutines
+// (provided the underlying io.ReaderAt is). For the same reason it has no TryXXX() methods.
+type ReaderAt struct {
+	in   io.ReaderAt // the source, nil if data is used
+	data []byte      // the source if in is nil
+	lsb  bool        // tells if least-significant-bit-first order is used
+}
+
+// NewReaderAt returns a new ReaderAt using the specified io.ReaderAt as the input (source).
+//
+// The returned ReaderAt uses highest-bits-first order, see NewReaderAtLSB()
+// for least-significant-bit-first order.
+func NewReaderAt(in io.ReaderAt) *ReaderAt {
+	return &ReaderAt{in: in}
+}
+
+// NewReaderAtLSB returns a new ReaderAt using the specified io.ReaderAt as the input (source),
+// which uses least-significant-bit-first order.
+func NewReaderAtLSB(in io.ReaderAt) *ReaderAt {
+	return &ReaderAt{in: in, lsb: true}
+}
+
+// NewReaderAtBytes returns a new ReaderAt using the specified byte slice as the input (source).
+// The byte slice must not be modified while the ReaderAt is in use.
+//
+// The returned ReaderAt uses highest-bits-first order, see NewReaderAtBytesLSB()
+// for least-significant-bit-first order.
+func NewReaderAtBytes(data []byte) *ReaderAt {
+	return &ReaderAt{data: data}
+}
+
+// NewReaderAtBytesLSB returns a new ReaderAt using the specified byte slice as the input (source),
+// which uses least-significant-bit-first order.
+// The byte slice must not be modified while the ReaderAt is in use.
+func NewReaderAtBytesLSB(data []byte) *ReaderAt {
+	return &ReaderAt{data: data, lsb: true}
+}
+
+// ReadBitsAt reads n bits starting at bit offset off, and returns them as the lowest n bits of u.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
+// off must not be negative, else ErrInvalidParameter is returned.
+//
+// If no bits are available at off, io.EOF is returned. If fewer than n bits are available,
+// they are returned in a *PartialReadError (wrapped in a *BitError).
+func (r *ReaderAt) ReadBitsAt(off int64, n uint8) (u uint64, err error) {
+	if u, err = r.readBitsAt(off, n); err != nil {
+		err = wrapError("ReadBitsAt", int64(n), off, err)
+	}
+	return
+}
+
+// ReadBoolAt reads the bit at bit offset off, and returns true if it is 1.
+// off must not be negative, else ErrInvalidParameter is returned.
+func (r *ReaderAt) ReadBoolAt(off int64) (b bool, err error) {
+	u, err := r.readBitsAt(off, 1)
+	if err != nil {
+		return false, wrapError("ReadBoolAt", 1, off, err)
+	}
+	return u == 1, nil
+}
+
+// readBitsAt is the implementation of ReadBitsAt(), errors are not wrapped.
+func (r *ReaderAt) readBitsAt(off int64, n uint8) (u uint64, err error) {
+	if n > 64 {
+		return 0, ErrInvalidBitCount
+	}
+	if off < 0 {
+		return 0, ErrInvalidParameter
+	}
+
+	var p [9]byte // n bits starting at any bit of a byte span at most 9 bytes
+	skip := uint8(off % 8)
+	nb := (int(skip) + int(n) + 7) / 8 // number of bytes to read
+
+	var m int
+	if r.in == nil {
+		if i := off / 8; i < int64(len(r.data)) {
+			m = copy(p[:nb], r.data[i:])
+		}
+	} else {
+		m, err = r.in.ReadAt(p[:nb], off/8)
+	}
+	if m == nb {
+		return extractBits(&p, skip, n, r.lsb), nil
+	}
+
+	// Fewer bytes available than needed
+	for i := m; i < nb; i++ {
+		p[i] = 0 // ReadAt() may use all of p as scratch space
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	avail := m*8 - int(skip)
+	if avail <= 0 {
+		return 0, err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return 0, &PartialReadError{Bits: uint8(avail), Value: extractBits(&p, skip, uint8(avail), r.lsb), Err: err}
+}
+
+// extractBits returns n bits of p starting at bit skip (skip < 8),
+// as the lowest n bits of u.
+func extractBits(p *[9]byte, skip, n uint8, lsb bool) (u uint64) {
+	mask := uint64(1)<<n - 1
+	if lsb {
+		acc := binary.LittleEndian.Uint64(p[:8])
+		return (acc>>skip | uint64(p[8])<<(64-skip)) & mask // shifting by 64 gives 0
+	}
+
+	acc := binary.BigEndian.Uint64(p[:8])
+	s := 72 - skip - n // number of bits after the needed ones in the 72 bits of p
+	if s >= 8 {
+		return acc >> (s - 8) & mask
+	}
+	return (acc<<(8-s) | uint64(p[8])>>s) & mask
+}
@@ -0,0 +1,113 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestReaderAt(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	data := make([]byte, 1000)
+	rand.Read(data)
+
+	for _, lsb := range []bool{false, true} {
+		newReader := NewReader
+		readerAts := []*ReaderAt{NewReaderAt(bytes.NewReader(data)), NewReaderAtBytes(data)}
+		if lsb {
+			newReader = NewReaderLSB
+			readerAts = []*ReaderAt{NewReaderAtLSB(bytes.NewReader(data)), NewReaderAtBytesLSB(data)}
+		}
+
+		for _, ra := range readerAts {
+			for i := 0; i < 1000; i++ {
+				off := rand.Int63n(int64(len(data))*8 - 64)
+				n := uint8(rand.Intn(65))
+				r := newReader(bytes.NewReader(data))
+				r.SkipBits(off)
+				exp, _ := r.ReadBits(n)
+				expEq(exp)(ra.ReadBitsAt(off, n))
+				u, _ := ra.ReadBitsAt(off, 1)
+				expEq(u == 1)(ra.ReadBoolAt(off))
+			}
+
+			// At the end of the input
+			end := int64(len(data)) * 8
+			r := newReader(bytes.NewReader(data))
+			r.SkipBits(end - 10)
+			exp, _ := r.ReadBits(10)
+			expEq(exp)(ra.ReadBitsAt(end-10, 10))
+			_, err := ra.ReadBitsAt(end-10, 64)
+			eq(PartialReadError{Bits: 10, Value: exp, Err: io.ErrUnexpectedEOF}, partial(err))
+			eq(BitError{Op: "ReadBitsAt", Width: 64, Offset: end - 10, Err: bitError(err).Err}, bitError(err))
+			_, err = ra.ReadBitsAt(end, 1)
+			eq(io.EOF, err)
+			_, err = ra.ReadBoolAt(end + 100)
+			eq(io.EOF, err)
+
+			_, err = ra.ReadBitsAt(0, 65)
+			eq(BitError{Op: "ReadBitsAt", Width: 65, Err: ErrInvalidBitCount}, bitError(err))
+			_, err = ra.ReadBoolAt(-1)
+			eq(BitError{Op: "ReadBoolAt", Width: 1, Offset: -1, Err: ErrInvalidParameter}, bitError(err))
+		}
+	}
+
+	// Errors of the source are kept
+	ra := NewReaderAt(iotestErrReaderAt{})
+	expEq(uint64(0xff))(ra.ReadBitsAt(0, 8)) // All needed bytes are read
+	_, err := ra.ReadBitsAt(8, 8)
+	eq(BitError{Op: "ReadBitsAt", Width: 8, Offset: 8, Err: errReadAt}, bitError(err))
+}
+
+var errReadAt = errors.New("read at error")
+
+// iotestErrReaderAt returns a single byte of 0xff, then errReadAt.
+type iotestErrReaderAt struct{}
+
+func (iotestErrReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off == 0 && len(p) > 0 {
+		p[0] = 0xff
+		return 1, errReadAt
+	}
+	return 0, errReadAt
+}
+
+func TestReaderAtPartialSource(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	ra := NewReaderAt(iotestErrReaderAt{})
+	_, err := ra.ReadBitsAt(4, 12)
+	eq(PartialReadError{Bits: 4, Value: 0x0f, Err: errReadAt}, partial(err))
+}
+
+func TestReaderAtConcurrent(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	data := make([]byte, 1<<16)
+	rand.Read(data)
+	ra := NewReaderAtBytes(data)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// Each goroutine decodes its own block as 16-bit values
+			block := data[g*1024 : (g+1)*1024]
+			for i := 0; i < len(block); i += 2 {
+				u, err := ra.ReadBitsAt(int64(g*1024+i)*8, 16)
+				if err != nil || u != uint64(block[i])<<8|uint64(block[i+1]) {
+					eq(uint64(block[i])<<8|uint64(block[i+1]), u)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
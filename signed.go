@@ -0,0 +1,398 @@
+/*
+
+Signed integers.
+
+*/
+
+package bitio
+
+import "math/bits"
+
+// ReadSigned reads n bits and returns them as an n-bit two's complement
+// signed integer, sign extended to int64.
+func (r *Reader) ReadSigned(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSigned(r, n); err != nil {
+		err = wrapError("ReadSigned", int64(n), off, err)
+	}
+	return
+}
+
+// TryReadSigned tries to read an n-bit two's complement signed integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadSigned(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSigned(n)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadSigned reads n bits and returns them as an n-bit two's complement
+// signed integer, sign extended to int64, and counts the number of bits read.
+func (r *CountReader) ReadSigned(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSigned(r, n); err != nil {
+		err = wrapError("ReadSigned", int64(n), off, err)
+	}
+	return
+}
+
+// TryReadSigned tries to read an n-bit two's complement signed integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSigned(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadSigned(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSigned(n)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteSigned writes v as an n-bit two's complement signed integer.
+//
+// If v does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *Writer) WriteSigned(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSigned(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteSigned", int64(n), off, err)
+	}
+	return
+}
+
+// TryWriteSigned tries to write v as an n-bit two's complement signed integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSigned(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteSigned(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSigned(v, n)
+		w.checkTry()
+	}
+}
+
+// WriteSigned writes v as an n-bit two's complement signed integer,
+// and counts the number of bits written.
+//
+// If v does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *CountWriter) WriteSigned(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSigned(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteSigned", int64(n), off, err)
+	}
+	return
+}
+
+// TryWriteSigned tries to write v as an n-bit two's complement signed integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSigned(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteSigned(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSigned(v, n)
+		w.checkTry()
+	}
+}
+
+// ReadSignMagnitude reads an n-bit sign-magnitude integer: the highest bit
+// of the n bits is the sign (1 means negative), the lower n-1 bits are the
+// magnitude. Negative zero is returned as 0.
+func (r *Reader) ReadSignMagnitude(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSignMagnitude(r, n); err != nil {
+		err = wrapError("ReadSignMagnitude", int64(n), off, err)
+	}
+	return
+}
+
+// ReadBiased reads an n-bit biased (offset binary, excess-K) integer:
+// the returned value is the unsigned value of the n bits minus bias.
+// Offset binary as commonly used has a bias of 1<<(n-1).
+//
+// If the result does not fit into an int64, ErrOverflow is returned.
+func (r *Reader) ReadBiased(n uint8, bias uint64) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readBiased(r, n, bias); err != nil {
+		err = wrapError("ReadBiased", int64(n), off, err)
+	}
+	return
+}
+
+// TryReadSignMagnitude tries to read an n-bit sign-magnitude integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSignMagnitude(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadSignMagnitude(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSignMagnitude(n)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadBiased tries to read an n-bit biased integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBiased(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadBiased(n uint8, bias uint64) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadBiased(n, bias)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadSignMagnitude reads an n-bit sign-magnitude integer,
+// and counts the number of bits read.
+// The highest bit of the n bits is the sign (1 means negative),
+// the lower n-1 bits are the magnitude. Negative zero is returned as 0.
+func (r *CountReader) ReadSignMagnitude(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSignMagnitude(r, n); err != nil {
+		err = wrapError("ReadSignMagnitude", int64(n), off, err)
+	}
+	return
+}
+
+// ReadBiased reads an n-bit biased (offset binary, excess-K) integer,
+// and counts the number of bits read.
+// The returned value is the unsigned value of the n bits minus bias.
+// Offset binary as commonly used has a bias of 1<<(n-1).
+//
+// If the result does not fit into an int64, ErrOverflow is returned.
+func (r *CountReader) ReadBiased(n uint8, bias uint64) (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readBiased(r, n, bias); err != nil {
+		err = wrapError("ReadBiased", int64(n), off, err)
+	}
+	return
+}
+
+// TryReadSignMagnitude tries to read an n-bit sign-magnitude integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSignMagnitude(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadSignMagnitude(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSignMagnitude(n)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadBiased tries to read an n-bit biased integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadBiased(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadBiased(n uint8, bias uint64) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadBiased(n, bias)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteSignMagnitude writes v as an n-bit sign-magnitude integer: the highest bit
+// of the n bits is the sign (1 means negative), the lower n-1 bits are the
+// magnitude of v.
+//
+// If the magnitude of v does not fit into n-1 bits, only its lowest n-1 bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *Writer) WriteSignMagnitude(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSignMagnitude(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteSignMagnitude", int64(n), off, err)
+	}
+	return
+}
+
+// WriteBiased writes v as an n-bit biased (offset binary, excess-K) integer:
+// the unsigned value of the written n bits is v plus bias.
+// Offset binary as commonly used has a bias of 1<<(n-1).
+//
+// If v+bias does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *Writer) WriteBiased(v int64, n uint8, bias uint64) (err error) {
+	off := w.bitPos()
+	if err = writeBiased(w, v, n, bias, w.Strict); err != nil {
+		err = wrapError("WriteBiased", int64(n), off, err)
+	}
+	return
+}
+
+// TryWriteSignMagnitude tries to write v as an n-bit sign-magnitude integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSignMagnitude(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteSignMagnitude(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSignMagnitude(v, n)
+		w.checkTry()
+	}
+}
+
+// TryWriteBiased tries to write v as an n-bit biased integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBiased(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteBiased(v int64, n uint8, bias uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBiased(v, n, bias)
+		w.checkTry()
+	}
+}
+
+// WriteSignMagnitude writes v as an n-bit sign-magnitude integer,
+// and counts the number of bits written.
+// The highest bit of the n bits is the sign (1 means negative),
+// the lower n-1 bits are the magnitude of v.
+//
+// If the magnitude of v does not fit into n-1 bits, only its lowest n-1 bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *CountWriter) WriteSignMagnitude(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeSignMagnitude(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteSignMagnitude", int64(n), off, err)
+	}
+	return
+}
+
+// WriteBiased writes v as an n-bit biased (offset binary, excess-K) integer,
+// and counts the number of bits written.
+// The unsigned value of the written n bits is v plus bias.
+// Offset binary as commonly used has a bias of 1<<(n-1).
+//
+// If v+bias does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *CountWriter) WriteBiased(v int64, n uint8, bias uint64) (err error) {
+	off := w.bitPos()
+	if err = writeBiased(w, v, n, bias, w.Strict); err != nil {
+		err = wrapError("WriteBiased", int64(n), off, err)
+	}
+	return
+}
+
+// TryWriteSignMagnitude tries to write v as an n-bit sign-magnitude integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSignMagnitude(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteSignMagnitude(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSignMagnitude(v, n)
+		w.checkTry()
+	}
+}
+
+// TryWriteBiased tries to write v as an n-bit biased integer.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteBiased(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteBiased(v int64, n uint8, bias uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteBiased(v, n, bias)
+		w.checkTry()
+	}
+}
+
+// signExtend returns the lowest n bits of u as an n-bit two's complement
+// signed integer, sign extended to int64.
+func signExtend(u uint64, n uint8) int64 {
+	if n >= 64 {
+		return int64(u)
+	}
+	s := 64 - uint(n)
+	return int64(u<<s) >> s
+}
+
+// readSigned reads an n-bit two's complement signed integer from r.
+func readSigned(r bitReader, n uint8) (v int64, err error) {
+	u, err := r.ReadBits(n)
+	if err != nil {
+		return 0, err
+	}
+	return signExtend(u, n), nil
+}
+
+// writeSigned writes v as an n-bit two's complement signed integer to w.
+// If strict is true, ErrOverflow is returned if v does not fit into n bits.
+func writeSigned(w bitWriter, v int64, n uint8, strict bool) (err error) {
+	if strict && signExtend(uint64(v), n) != v {
+		return ErrOverflow
+	}
+	return w.WriteBits(uint64(v), n)
+}
+
+// readSignMagnitude reads an n-bit sign-magnitude integer from r.
+func readSignMagnitude(r bitReader, n uint8) (v int64, err error) {
+	u, err := r.ReadBits(n)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	sign := uint(n - 1)
+	v = int64(u & (1<<sign - 1))
+	if u>>sign&1 == 1 {
+		v = -v
+	}
+	return v, nil
+}
+
+// readBiased reads an n-bit biased integer from r.
+func readBiased(r bitReader, n uint8, bias uint64) (v int64, err error) {
+	u, err := r.ReadBits(n)
+	if err != nil {
+		return 0, err
+	}
+	d, borrow := bits.Sub64(u, bias, 0)
+	// u-bias is non-negative if there was no borrow
+	if (borrow == 0) != (int64(d) >= 0) {
+		return 0, ErrOverflow
+	}
+	return int64(d), nil
+}
+
+// writeSignMagnitude writes v as an n-bit sign-magnitude integer to w.
+// If strict is true, ErrOverflow is returned if the magnitude does not fit into n-1 bits.
+func writeSignMagnitude(w bitWriter, v int64, n uint8, strict bool) (err error) {
+	var u uint64 // magnitude
+	if v < 0 {
+		u = uint64(-v) // Proper for math.MinInt64 too
+	} else {
+		u = uint64(v)
+	}
+	if n == 0 {
+		if strict && u != 0 {
+			return ErrOverflow
+		}
+		return nil
+	}
+	sign := uint(n - 1)
+	if strict && u>>sign != 0 {
+		return ErrOverflow
+	}
+	u &= 1<<sign - 1
+	if v < 0 {
+		u |= 1 << sign
+	}
+	return w.WriteBits(u, n)
+}
+
+// writeBiased writes v as an n-bit biased integer to w.
+// If strict is true, ErrOverflow is returned if v+bias does not fit into n bits.
+func writeBiased(w bitWriter, v int64, n uint8, bias uint64, strict bool) (err error) {
+	var u uint64
+	var outOfRange uint64 // non-zero if v+bias is negative or is not less than 1<<64
+	if v < 0 {
+		u, outOfRange = bits.Sub64(bias, uint64(-v), 0)
+	} else {
+		u, outOfRange = bits.Add64(bias, uint64(v), 0)
+	}
+	if strict && (outOfRange != 0 || n < 64 && u>>n != 0) {
+		return ErrOverflow
+	}
+	return w.WriteBits(u, n)
+}
@@ -0,0 +1,282 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestSigned(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteSigned(-1, 1))  // 1
+	eq(nil, w.WriteSigned(-2, 3))  // 110
+	eq(nil, w.WriteSigned(3, 4))   // 0011
+	eq(nil, w.WriteSigned(-8, 4))  // 1000
+	eq(nil, w.WriteSigned(-3, 4))  // 1101
+	eq(nil, w.WriteSigned(17, 4))  // 0001 (truncated)
+	eq(nil, w.WriteSigned(-9, 4))  // 0111 (truncated)
+	eq(nil, w.WriteSigned(-1, 64)) // 64 1-bits
+	eq(nil, w.Close())
+	// 1110 0011 1000 1101 0001 0111, 1111...
+	eq(true, bytes.Equal(b.Bytes()[:3], []byte{0xe3, 0x8d, 0x17}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-1))(r.ReadSigned(1))
+	expEq(int64(-2))(r.ReadSigned(3))
+	expEq(int64(3))(r.ReadSigned(4))
+	expEq(int64(-8))(r.ReadSigned(4))
+	expEq(int64(-3))(r.ReadSigned(4))
+	expEq(int64(1))(r.ReadSigned(4))
+	expEq(int64(7))(r.ReadSigned(4))
+	expEq(int64(-1))(r.ReadSigned(64))
+	expEq(int64(0))(r.ReadSigned(0))
+}
+
+func TestSignedWidths(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	type value struct {
+		v int64
+		n uint8
+	}
+	var values []value
+	for n := uint8(1); n <= 64; n++ {
+		min, max := int64(-1)<<(n-1), int64(uint64(1)<<(n-1)-1)
+		values = append(values,
+			value{min, n}, value{max, n}, value{0, n}, value{-1, n},
+			value{rand.Int63() >> (64 - n), n}, value{-rand.Int63() >> (64 - n), n},
+		)
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		w.Strict = true
+		for _, v := range values {
+			eq(nil, w.WriteSigned(v.v, v.n))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, v := range values {
+			expEq(v.v)(r.ReadSigned(v.n))
+		}
+	}
+}
+
+func TestSignedStrict(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Strict = true
+	for n := uint8(1); n < 64; n++ {
+		min, max := int64(-1)<<(n-1), int64(uint64(1)<<(n-1)-1)
+		eq(true, errors.Is(w.WriteSigned(max+1, n), ErrOverflow))
+		eq(true, errors.Is(w.WriteSigned(min-1, n), ErrOverflow))
+	}
+	eq(true, errors.Is(w.WriteSigned(1, 0), ErrOverflow))
+	eq(nil, w.WriteSigned(0, 0))
+	w.TryWriteSigned(-5, 3)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	eq(nil, w.Close())
+	eq(0, b.Len())
+}
+
+func TestSignedTry(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.TryWriteSigned(-5, 7)
+	w.TryWriteSigned(100, 7) // truncated to -28
+	eq(nil, w.TryError)
+	eq(nil, w.Close())
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(int64(-5), r.TryReadSigned(7))
+	eq(int64(-28), r.TryReadSigned(7))
+	eq(nil, r.TryError)
+	eq(int64(0), r.TryReadSigned(7))
+	eq(true, r.TryError != nil)
+}
+
+func TestSignedCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteSigned(-3, 5))
+	w.TryWriteSigned(1000, 12)
+	eq(nil, w.TryError)
+	eq(int64(17), w.BitsCount)
+	w.Strict = true
+	eq(true, errors.Is(w.WriteSigned(1000, 10), ErrOverflow))
+	eq(int64(17), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-3))(r.ReadSigned(5))
+	eq(int64(5), r.BitsCount)
+	eq(int64(1000), r.TryReadSigned(12))
+	eq(nil, r.TryError)
+	eq(int64(17), r.BitsCount)
+}
+
+func TestSignMagnitudeBiased(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteSignMagnitude(-3, 4))  // 1011
+	eq(nil, w.WriteSignMagnitude(5, 4))   // 0101
+	eq(nil, w.WriteSignMagnitude(-12, 4)) // 1100 (truncated)
+	eq(nil, w.WriteBiased(-3, 4, 8))      // 0101
+	eq(nil, w.WriteBiased(7, 4, 8))       // 1111
+	eq(nil, w.WriteBiased(2, 4, 127))     // 0001 (truncated)
+	eq(nil, w.Close())
+	// 1011 0101 1100 0101 1111 0001
+	eq(true, bytes.Equal(b.Bytes(), []byte{0xb5, 0xc5, 0xf1}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-3))(r.ReadSignMagnitude(4))
+	expEq(int64(5))(r.ReadSignMagnitude(4))
+	expEq(int64(-4))(r.ReadSignMagnitude(4))
+	expEq(int64(-3))(r.ReadBiased(4, 8))
+	expEq(int64(7))(r.ReadBiased(4, 8))
+	expEq(int64(-126))(r.ReadBiased(4, 127))
+
+	// Negative zero
+	r = NewReader(bytes.NewBuffer([]byte{0x80}))
+	expEq(int64(0))(r.ReadSignMagnitude(1))
+	expEq(int64(0))(r.ReadSignMagnitude(0))
+	expEq(int64(0))(r.ReadSignMagnitude(7))
+}
+
+func TestSignMagnitudeBiasedWidths(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	type value struct {
+		v    int64
+		n    uint8
+		bias uint64
+	}
+	var sms, biased []value
+	for n := uint8(1); n <= 64; n++ {
+		max := int64(uint64(1)<<(n-1) - 1)
+		sms = append(sms,
+			value{max, n, 0}, value{-max, n, 0}, value{0, n, 0},
+			value{rand.Int63() >> (64 - n), n, 0}, value{-(rand.Int63() >> (64 - n)), n, 0},
+		)
+
+		// Offset binary: range is the same as of two's complement
+		bias := uint64(1) << (n - 1)
+		min := int64(-1) << (n - 1)
+		biased = append(biased,
+			value{min, n, bias}, value{max, n, bias}, value{0, n, bias}, value{-1, n, bias},
+			value{rand.Int63() >> (64 - n), n, bias}, value{-rand.Int63() >> (64 - n), n, bias},
+		)
+		// Excess-3 and zero bias
+		if n > 2 {
+			biased = append(biased, value{-3, n, 3}, value{max, n, 3})
+		}
+		biased = append(biased, value{0, n, 0}, value{max, n, 0})
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		w.Strict = true
+		for _, v := range sms {
+			eq(nil, w.WriteSignMagnitude(v.v, v.n))
+		}
+		for _, v := range biased {
+			eq(nil, w.WriteBiased(v.v, v.n, v.bias))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, v := range sms {
+			expEq(v.v)(r.ReadSignMagnitude(v.n))
+		}
+		for _, v := range biased {
+			expEq(v.v)(r.ReadBiased(v.n, v.bias))
+		}
+	}
+}
+
+func TestSignMagnitudeBiasedOverflow(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Strict = true
+	for n := uint8(1); n < 64; n++ {
+		max := int64(uint64(1)<<(n-1) - 1)
+		eq(true, errors.Is(w.WriteSignMagnitude(max+1, n), ErrOverflow))
+		eq(true, errors.Is(w.WriteSignMagnitude(-max-1, n), ErrOverflow))
+		eq(true, errors.Is(w.WriteBiased(max+1, n, 1<<(n-1)), ErrOverflow))
+		eq(true, errors.Is(w.WriteBiased(-max-2, n, 1<<(n-1)), ErrOverflow))
+	}
+	eq(true, errors.Is(w.WriteSignMagnitude(math.MinInt64, 64), ErrOverflow))
+	eq(true, errors.Is(w.WriteSignMagnitude(1, 0), ErrOverflow))
+	eq(nil, w.WriteSignMagnitude(0, 0))
+	eq(true, errors.Is(w.WriteBiased(-1, 64, 0), ErrOverflow))
+	eq(true, errors.Is(w.WriteBiased(1, 64, math.MaxUint64), ErrOverflow))
+	eq(true, errors.Is(w.WriteBiased(1, 0, 0), ErrOverflow))
+	w.TryWriteSignMagnitude(4, 3)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	eq(nil, w.Close())
+	eq(0, b.Len())
+
+	r := NewReader(bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}))
+	_, err := r.ReadBiased(64, 1)
+	eq(true, errors.Is(err, ErrOverflow))
+	_, err = r.ReadBiased(64, math.MaxUint64)
+	eq(true, errors.Is(err, ErrOverflow))
+}
+
+func TestSignMagnitudeBiasedCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteSignMagnitude(-3, 5))
+	eq(nil, w.WriteBiased(-3, 6, 32))
+	w.TryWriteSignMagnitude(100, 8)
+	w.TryWriteBiased(100, 10, 15)
+	eq(nil, w.TryError)
+	eq(int64(29), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-3))(r.ReadSignMagnitude(5))
+	expEq(int64(-3))(r.ReadBiased(6, 32))
+	eq(int64(11), r.BitsCount)
+	eq(int64(100), r.TryReadSignMagnitude(8))
+	eq(int64(100), r.TryReadBiased(10, 15))
+	eq(nil, r.TryError)
+	eq(int64(29), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(int64(-3), rr.TryReadSignMagnitude(5))
+	eq(int64(-3), rr.TryReadBiased(6, 32))
+	eq(nil, rr.TryError)
+}
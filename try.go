@@ -0,0 +1,56 @@
+/*
+
+Panicking mode of the TryXXX() methods.
+
+*/
+
+package bitio
+
+// tryPanic is the value TryXXX() methods panic with if TryPanic is set.
+type tryPanic struct {
+	err error
+}
+
+// checkTry panics if TryPanic is set and a TryError occurred.
+func (r *Reader) checkTry() {
+	if r.TryPanic && r.TryError != nil {
+		panic(tryPanic{r.TryError})
+	}
+}
+
+// checkTry panics if TryPanic is set and a TryError occurred.
+func (w *Writer) checkTry() {
+	if w.TryPanic && w.TryError != nil {
+		panic(tryPanic{w.TryError})
+	}
+}
+
+// Catch calls f, and recovers from the panic of a failed TryXXX() call
+// of a Reader or Writer (or CountReader or CountWriter) having TryPanic set.
+// The error of the failed call is returned, or nil if f completes normally.
+//
+// Other panics are not recovered, they are propagated to the caller.
+//
+// For example:
+//
+//	r := NewReader(in)
+//	r.TryPanic = true
+//	err := Catch(func() {
+//	    a := r.TryReadBits(4)
+//	    b := r.TryReadUE(0)
+//	    // ...
+//	})
+func Catch(f func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			tp, ok := v.(tryPanic)
+			if !ok {
+				panic(v)
+			}
+			err = tp.err
+		}
+	}()
+
+	f()
+	return nil
+}
@@ -0,0 +1,71 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestTryPanic(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	r := NewReader(bytes.NewBuffer([]byte{0x8f}))
+	r.TryPanic = true
+	var a, b uint64
+	calls := 0
+	err := Catch(func() {
+		a = r.TryReadBits(4)
+		b = r.TryReadBits(3)
+		r.TryReadBits(8)
+		calls++ // Not reached
+	})
+	eq(uint64(0x08), a)
+	eq(uint64(0x07), b)
+	eq(0, calls)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	eq(err, r.TryError)
+
+	// Try methods are no-ops after a TryError, they don't panic again
+	eq(nil, Catch(func() { r.TryReadBool() }))
+
+	// Composite codes and CountReader
+	cr := NewCountReader(bytes.NewBuffer([]byte{0x00}))
+	cr.TryPanic = true
+	err = Catch(func() {
+		cr.TryReadUE(0)
+	})
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	eq(int64(8), cr.BitsCount)
+
+	w := NewCountWriter(&errWriter{})
+	w.TryPanic = true
+	err = Catch(func() {
+		w.TryWriteBool(true)
+		w.TryAlign()
+		w.TryWriteBool(true)
+	})
+	eq(BitError{Op: "Align", Offset: 1, Err: bitError(err).Err}, bitError(err))
+	eq(int64(8), w.BitsCount)
+
+	// Nothing happens without an error
+	w = NewCountWriter(&bytes.Buffer{})
+	w.TryPanic = true
+	eq(nil, Catch(func() {
+		w.TryWriteUE(100, 0)
+		w.TryAlign()
+	}))
+
+	// Without TryPanic errors are only stored
+	r = NewReader(bytes.NewBuffer(nil))
+	eq(nil, Catch(func() { r.TryReadBits(1) }))
+	eq(io.EOF, r.TryError)
+
+	// Other panics are propagated
+	defer func() {
+		eq("other", recover())
+	}()
+	Catch(func() { panic("other") })
+}
@@ -0,0 +1,179 @@
+/*
+
+Unary coding.
+
+*/
+
+package bitio
+
+import (
+	"math"
+	"math/bits"
+)
+
+// ReadUnary reads a unary code: counts the consecutive bits different from stopBit
+// until a stopBit is encountered (which is also consumed), and returns their number.
+//
+// max is the max number of bits allowed before the stop bit, it must not be negative.
+// If more than max bits precede the stop bit, reading stops after max+1 bits,
+// and n = max+1 is returned along with ErrOverflow. In case of other errors
+// n is the number of bits consumed.
+func (r *Reader) ReadUnary(stopBit bool, max int) (n int, err error) {
+	if n, err = r.readUnary(stopBit, max); err != nil {
+		err = wrapError("ReadUnary", 0, r.bitPos()-int64(n), err)
+	}
+	return
+}
+
+// readUnary is the implementation of ReadUnary(), errors are not wrapped.
+func (r *Reader) readUnary(stopBit bool, max int) (n int, err error) {
+	if max < 0 {
+		return 0, ErrInvalidParameter
+	}
+
+	for {
+		if r.bits == 0 {
+			if err = r.ensure(1); err != nil {
+				if n > 0 {
+					err = noEOF(err) // Bits of the code have been consumed
+				}
+				return
+			}
+			r.fill()
+		}
+
+		// Count the run in acc at once, a sentinel bit after the unread bits
+		// makes sure the run doesn't extend beyond them.
+		var run int
+		if r.lsb {
+			if stopBit {
+				run = bits.TrailingZeros64(r.acc | 1<<r.bits)
+			} else {
+				run = bits.TrailingZeros64(^r.acc) // bits above the unread ones are 1s in ^acc
+			}
+		} else {
+			if stopBit {
+				run = bits.LeadingZeros64(r.acc | 1<<(63-r.bits)) // 1<<(63-64) is 0 if r.bits is 64
+			} else {
+				run = bits.LeadingZeros64(^r.acc) // bits below the unread ones are 1s in ^acc
+			}
+		}
+
+		if n+run > max {
+			r.take(uint8(max + 1 - n))
+			return max + 1, ErrOverflow
+		}
+		if run < int(r.bits) {
+			// Stop bit found
+			r.take(uint8(run) + 1)
+			return n + run, nil
+		}
+		n += run
+		r.take(r.bits)
+	}
+}
+
+// TryReadUnary tries to read a unary code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUnary(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUnary(stopBit bool, max int) (n int) {
+	if r.TryError == nil {
+		n, r.TryError = r.ReadUnary(stopBit, max)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadUnary reads a unary code: counts the consecutive bits different from stopBit
+// until a stopBit is encountered (which is also consumed), and returns their number.
+// It also counts the number of bits read.
+//
+// max is the max number of bits allowed before the stop bit, it must not be negative.
+// If more than max bits precede the stop bit, reading stops after max+1 bits,
+// and n = max+1 is returned along with ErrOverflow. In case of other errors
+// n is the number of bits consumed.
+func (r *CountReader) ReadUnary(stopBit bool, max int) (n int, err error) {
+	n, err = r.Reader.ReadUnary(stopBit, max)
+	r.BitsCount += int64(n)
+	if err == nil {
+		r.BitsCount++ // the stop bit
+	}
+	return
+}
+
+// TryReadUnary tries to read a unary code.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUnary(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUnary(stopBit bool, max int) (n int) {
+	if r.TryError == nil {
+		n, r.TryError = r.ReadUnary(stopBit, max)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteUnary writes a unary code: n (n >= 0) bits different from stopBit,
+// followed by a stopBit.
+func (w *Writer) WriteUnary(n int, stopBit bool) (err error) {
+	off := w.bitPos()
+	if err = writeUnary(w, n, stopBit); err != nil {
+		err = wrapError("WriteUnary", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUnary tries to write a unary code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUnary(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUnary(n int, stopBit bool) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUnary(n, stopBit)
+		w.checkTry()
+	}
+}
+
+// WriteUnary writes a unary code: n (n >= 0) bits different from stopBit,
+// followed by a stopBit. It also counts the number of bits written.
+func (w *CountWriter) WriteUnary(n int, stopBit bool) (err error) {
+	off := w.bitPos()
+	if err = writeUnary(w, n, stopBit); err != nil {
+		err = wrapError("WriteUnary", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUnary tries to write a unary code.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUnary(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUnary(n int, stopBit bool) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUnary(n, stopBit)
+		w.checkTry()
+	}
+}
+
+// writeUnary writes a unary code to w: n bits different from stopBit,
+// terminated by a stopBit.
+func writeUnary(w bitWriter, n int, stopBit bool) (err error) {
+	if n < 0 {
+		return ErrInvalidParameter
+	}
+
+	var run uint64 // bits of the run
+	if !stopBit {
+		run = math.MaxUint64
+	}
+	for ; n >= 64; n -= 64 {
+		if err = w.WriteBits(run, 64); err != nil {
+			return
+		}
+	}
+	if err = w.WriteBits(run, uint8(n)); err != nil {
+		return
+	}
+	return w.WriteBool(stopBit)
+}
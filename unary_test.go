@@ -0,0 +1,131 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestUnary(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteUnary(3, true))  // 0001
+	eq(nil, w.WriteUnary(0, true))  // 1
+	eq(nil, w.WriteUnary(2, false)) // 110
+	eq(nil, w.WriteUnary(9, true))  // 0000000001
+	eq(nil, w.Close())
+	// 0001 1110 0000 0000 0100 0000
+	eq(true, bytes.Equal(b.Bytes(), []byte{0x1e, 0x00, 0x40}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(3)(r.ReadUnary(true, 100))
+	expEq(0)(r.ReadUnary(true, 100))
+	expEq(2)(r.ReadUnary(false, 100))
+	expEq(9)(r.ReadUnary(true, 9))
+	expEq(uint64(0))(r.ReadBits(6))
+	n, err := r.ReadUnary(true, 100)
+	eq(0, n)
+	eq(io.EOF, err)
+
+	// EOF in the middle of a run
+	r = NewReader(bytes.NewBuffer([]byte{0xf0}))
+	expEq(uint64(0x0f))(r.ReadBits(4))
+	n, err = r.ReadUnary(true, 100)
+	eq(4, n)
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestUnaryChain(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	runs := make([]int, 10000)
+	for i := range runs {
+		runs[i] = rand.Intn(20)
+		if i%100 == 0 {
+			runs[i] = rand.Intn(1000)
+		}
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for i, n := range runs {
+			eq(nil, w.WriteUnary(n, i%3 == 0))
+			if i%7 == 0 {
+				eq(nil, w.WriteBits(uint64(i), 5))
+			}
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for i, n := range runs {
+			expEq(n)(r.ReadUnary(i%3 == 0, 1000))
+			if i%7 == 0 {
+				expEq(uint64(i) & 0x1f)(r.ReadBits(5))
+			}
+		}
+	}
+}
+
+func TestUnaryErrors(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	w := NewWriter(&bytes.Buffer{})
+	eq(true, errors.Is(w.WriteUnary(-1, true), ErrInvalidParameter))
+	w.TryWriteUnary(-1, false)
+	eq(true, errors.Is(w.TryError, ErrInvalidParameter))
+
+	r := NewReader(bytes.NewBuffer([]byte{0x00, 0x01, 0x80}))
+	_, err := r.ReadUnary(true, -1)
+	eq(true, errors.Is(err, ErrInvalidParameter))
+	n, err := r.ReadUnary(true, 14)
+	eq(15, n)
+	eq(true, errors.Is(err, ErrOverflow))
+	n, err = r.ReadUnary(true, 0)
+	eq(0, n)
+	eq(nil, err)
+	n, err = r.ReadUnary(false, 0)
+	eq(1, n)
+	eq(true, errors.Is(err, ErrOverflow))
+
+	r = NewReader(bytes.NewBuffer([]byte{0x00}))
+	eq(1, r.TryReadUnary(true, 0))
+	eq(true, errors.Is(r.TryError, ErrOverflow))
+}
+
+func TestUnaryCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteUnary(3, true))
+	eq(int64(4), w.BitsCount)
+	w.TryWriteUnary(100, false)
+	eq(nil, w.TryError)
+	eq(int64(105), w.BitsCount)
+	eq(nil, w.WriteUnary(10, true))
+	eq(int64(116), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(3)(r.ReadUnary(true, 10))
+	eq(int64(4), r.BitsCount)
+	eq(100, r.TryReadUnary(false, 100))
+	eq(nil, r.TryError)
+	eq(int64(105), r.BitsCount)
+	n, err := r.ReadUnary(true, 5)
+	eq(6, n)
+	eq(true, errors.Is(err, ErrOverflow))
+	eq(int64(111), r.BitsCount)
+}
@@ -0,0 +1,367 @@
+/*
+
+Varint (LEB128) coding.
+
+*/
+
+package bitio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Varints are read and written byte-by-byte using ReadByte() and Write(),
+// so they may start at any bit position, the stream does not need to be aligned.
+//
+// Uvarint is the same as unsigned LEB128. Varint is the zigzag encoded signed
+// varint of encoding/binary and Protocol Buffers (sint64), SLEB128 is the signed
+// LEB128 as used by DWARF and WebAssembly.
+//
+// Varints longer than binary.MaxVarintLen64 bytes or whose value does not fit into 64 bits
+// result in ErrOverflow. If the input ends in the middle of a varint, io.ErrUnexpectedEOF is returned.
+
+// ReadUvarint reads an unsigned varint (unsigned LEB128).
+func (r *Reader) ReadUvarint() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readUvarint(r); err != nil {
+		err = wrapError("ReadUvarint", 0, off, err)
+	}
+	return
+}
+
+// ReadVarint reads a zigzag encoded signed varint.
+func (r *Reader) ReadVarint() (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readVarint(r); err != nil {
+		err = wrapError("ReadVarint", 0, off, err)
+	}
+	return
+}
+
+// ReadSLEB128 reads a signed LEB128 value.
+func (r *Reader) ReadSLEB128() (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSLEB128(r); err != nil {
+		err = wrapError("ReadSLEB128", 0, off, err)
+	}
+	return
+}
+
+// TryReadUvarint tries to read an unsigned varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUvarint(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadUvarint() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUvarint()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadVarint tries to read a zigzag encoded signed varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadVarint(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadVarint() (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadVarint()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadSLEB128 tries to read a signed LEB128 value.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSLEB128(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadSLEB128() (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSLEB128()
+		r.checkTry()
+	}
+	return
+}
+
+// ReadUvarint reads an unsigned varint (unsigned LEB128),
+// and counts the number of bits read.
+func (r *CountReader) ReadUvarint() (u uint64, err error) {
+	off := r.bitPos()
+	if u, err = readUvarint(r); err != nil {
+		err = wrapError("ReadUvarint", 0, off, err)
+	}
+	return
+}
+
+// ReadVarint reads a zigzag encoded signed varint,
+// and counts the number of bits read.
+func (r *CountReader) ReadVarint() (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readVarint(r); err != nil {
+		err = wrapError("ReadVarint", 0, off, err)
+	}
+	return
+}
+
+// ReadSLEB128 reads a signed LEB128 value,
+// and counts the number of bits read.
+func (r *CountReader) ReadSLEB128() (v int64, err error) {
+	off := r.bitPos()
+	if v, err = readSLEB128(r); err != nil {
+		err = wrapError("ReadSLEB128", 0, off, err)
+	}
+	return
+}
+
+// TryReadUvarint tries to read an unsigned varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadUvarint(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadUvarint() (u uint64) {
+	if r.TryError == nil {
+		u, r.TryError = r.ReadUvarint()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadVarint tries to read a zigzag encoded signed varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadVarint(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadVarint() (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadVarint()
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadSLEB128 tries to read a signed LEB128 value.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadSLEB128(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadSLEB128() (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadSLEB128()
+		r.checkTry()
+	}
+	return
+}
+
+// WriteUvarint writes u as an unsigned varint (unsigned LEB128).
+func (w *Writer) WriteUvarint(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeUvarint(w, u); err != nil {
+		err = wrapError("WriteUvarint", 0, off, err)
+	}
+	return
+}
+
+// WriteVarint writes v as a zigzag encoded signed varint.
+func (w *Writer) WriteVarint(v int64) (err error) {
+	off := w.bitPos()
+	if err = writeVarint(w, v); err != nil {
+		err = wrapError("WriteVarint", 0, off, err)
+	}
+	return
+}
+
+// WriteSLEB128 writes v as a signed LEB128 value.
+func (w *Writer) WriteSLEB128(v int64) (err error) {
+	off := w.bitPos()
+	if err = writeSLEB128(w, v); err != nil {
+		err = wrapError("WriteSLEB128", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUvarint tries to write u as an unsigned varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUvarint(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteUvarint(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUvarint(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteVarint tries to write v as a zigzag encoded signed varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteVarint(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteVarint(v int64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteVarint(v)
+		w.checkTry()
+	}
+}
+
+// TryWriteSLEB128 tries to write v as a signed LEB128 value.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSLEB128(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteSLEB128(v int64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSLEB128(v)
+		w.checkTry()
+	}
+}
+
+// WriteUvarint writes u as an unsigned varint (unsigned LEB128),
+// and counts the number of bits written.
+func (w *CountWriter) WriteUvarint(u uint64) (err error) {
+	off := w.bitPos()
+	if err = writeUvarint(w, u); err != nil {
+		err = wrapError("WriteUvarint", 0, off, err)
+	}
+	return
+}
+
+// WriteVarint writes v as a zigzag encoded signed varint,
+// and counts the number of bits written.
+func (w *CountWriter) WriteVarint(v int64) (err error) {
+	off := w.bitPos()
+	if err = writeVarint(w, v); err != nil {
+		err = wrapError("WriteVarint", 0, off, err)
+	}
+	return
+}
+
+// WriteSLEB128 writes v as a signed LEB128 value,
+// and counts the number of bits written.
+func (w *CountWriter) WriteSLEB128(v int64) (err error) {
+	off := w.bitPos()
+	if err = writeSLEB128(w, v); err != nil {
+		err = wrapError("WriteSLEB128", 0, off, err)
+	}
+	return
+}
+
+// TryWriteUvarint tries to write u as an unsigned varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteUvarint(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteUvarint(u uint64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteUvarint(u)
+		w.checkTry()
+	}
+}
+
+// TryWriteVarint tries to write v as a zigzag encoded signed varint.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteVarint(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteVarint(v int64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteVarint(v)
+		w.checkTry()
+	}
+}
+
+// TryWriteSLEB128 tries to write v as a signed LEB128 value.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteSLEB128(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteSLEB128(v int64) {
+	if w.TryError == nil {
+		w.TryError = w.WriteSLEB128(v)
+		w.checkTry()
+	}
+}
+
+// readLEB128 reads the bytes of a LEB128 value from r.
+// It returns the assembled 7-bit groups, the number of bytes and the last byte read.
+func readLEB128(r io.ByteReader) (u uint64, n int, last byte, err error) {
+	for n < binary.MaxVarintLen64 {
+		if last, err = r.ReadByte(); err != nil {
+			if n > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, n, 0, err
+		}
+		u |= uint64(last&0x7f) << (7 * uint(n))
+		n++
+		if last < 0x80 {
+			return u, n, last, nil
+		}
+	}
+	return 0, n, 0, ErrOverflow
+}
+
+// readUvarint reads an unsigned varint from r.
+func readUvarint(r io.ByteReader) (u uint64, err error) {
+	u, n, last, err := readLEB128(r)
+	if err != nil {
+		return 0, err
+	}
+	// The 10th byte may only hold the highest bit of the value
+	if n == binary.MaxVarintLen64 && last > 1 {
+		return 0, ErrOverflow
+	}
+	return u, nil
+}
+
+// readVarint reads a zigzag encoded signed varint from r.
+func readVarint(r io.ByteReader) (v int64, err error) {
+	u, err := readUvarint(r)
+	return ZigzagDecode(u), err
+}
+
+// readSLEB128 reads a signed LEB128 value from r.
+func readSLEB128(r io.ByteReader) (v int64, err error) {
+	u, n, last, err := readLEB128(r)
+	if err != nil {
+		return 0, err
+	}
+	if n == binary.MaxVarintLen64 {
+		// The 10th byte holds the highest bit of the value,
+		// its other bits must be the extension of it.
+		if last != 0 && last != 0x7f {
+			return 0, ErrOverflow
+		}
+		return int64(u), nil
+	}
+	// Bit 6 of the last byte is the sign bit
+	if last&0x40 != 0 {
+		u |= ^uint64(0) << (7 * uint(n))
+	}
+	return int64(u), nil
+}
+
+// writeUvarint writes u as an unsigned varint to w.
+func writeUvarint(w io.Writer, u uint64) (err error) {
+	var buf [binary.MaxVarintLen64]byte
+	_, err = w.Write(buf[:binary.PutUvarint(buf[:], u)])
+	return
+}
+
+// writeVarint writes v as a zigzag encoded signed varint to w.
+func writeVarint(w io.Writer, v int64) (err error) {
+	var buf [binary.MaxVarintLen64]byte
+	_, err = w.Write(buf[:binary.PutVarint(buf[:], v)])
+	return
+}
+
+// writeSLEB128 writes v as a signed LEB128 value to w.
+func writeSLEB128(w io.Writer, v int64) (err error) {
+	var buf [binary.MaxVarintLen64]byte
+	n := 0
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		// Done if the remaining bits are the extension of the sign bit (bit 6) of b
+		if v == 0 && b&0x40 == 0 || v == -1 && b&0x40 != 0 {
+			buf[n] = b
+			n++
+			break
+		}
+		buf[n] = b | 0x80
+		n++
+	}
+	_, err = w.Write(buf[:n])
+	return
+}
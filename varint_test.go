@@ -0,0 +1,182 @@
+package bitio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestVarint(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteUvarint(300))           // ac 02
+	eq(nil, w.WriteVarint(-1))             // 01
+	eq(nil, w.WriteVarint(64))             // 80 01
+	eq(nil, w.WriteSLEB128(-123456))       // c0 bb 78
+	eq(nil, w.WriteSLEB128(127))           // ff 00
+	eq(nil, w.WriteSLEB128(-128))          // 80 7f
+	eq(nil, w.WriteSLEB128(63))            // 3f
+	eq(nil, w.WriteSLEB128(-64))           // 40
+	eq(nil, w.WriteSLEB128(math.MaxInt64)) // ff ff ff ff ff ff ff ff ff 00
+	eq(nil, w.Close())
+	exp := []byte{
+		0xac, 0x02, 0x01, 0x80, 0x01, 0xc0, 0xbb, 0x78, 0xff, 0x00, 0x80, 0x7f, 0x3f, 0x40,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00,
+	}
+	eq(true, bytes.Equal(b.Bytes(), exp))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(uint64(300))(r.ReadUvarint())
+	expEq(int64(-1))(r.ReadVarint())
+	expEq(int64(64))(r.ReadVarint())
+	expEq(int64(-123456))(r.ReadSLEB128())
+	expEq(int64(127))(r.ReadSLEB128())
+	expEq(int64(-128))(r.ReadSLEB128())
+	expEq(int64(63))(r.ReadSLEB128())
+	expEq(int64(-64))(r.ReadSLEB128())
+	expEq(int64(math.MaxInt64))(r.ReadSLEB128())
+	_, err := r.ReadUvarint()
+	eq(io.EOF, err)
+}
+
+func TestVarintUnaligned(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	values := []uint64{0, 1, 127, 128, 300, 1<<63 - 1, 1 << 63, math.MaxUint64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Uint64()>>uint(rand.Intn(64)))
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		for i, u := range values {
+			eq(nil, w.WriteBits(uint64(i), uint8(i%8)))
+			eq(nil, w.WriteUvarint(u))
+			eq(nil, w.WriteVarint(int64(u)))
+			eq(nil, w.WriteSLEB128(int64(u)))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for i, u := range values {
+			expEq(uint64(i) & (1<<uint(i%8) - 1))(r.ReadBits(uint8(i % 8)))
+			expEq(u)(r.ReadUvarint())
+			expEq(int64(u))(r.ReadVarint())
+			expEq(int64(u))(r.ReadSLEB128())
+		}
+	}
+
+	// Aligned output must be identical to that of encoding/binary
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	var exp []byte
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, u := range values {
+		eq(nil, w.WriteUvarint(u))
+		eq(nil, w.WriteVarint(int64(u)))
+		exp = append(exp, buf[:binary.PutUvarint(buf, u)]...)
+		exp = append(exp, buf[:binary.PutVarint(buf, int64(u))]...)
+	}
+	eq(nil, w.Close())
+	eq(true, bytes.Equal(b.Bytes(), exp))
+}
+
+func TestVarintErrors(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	long := bytes.Repeat([]byte{0x80}, 10)
+	r := NewReader(bytes.NewBuffer(append(long, 0)))
+	_, err := r.ReadUvarint()
+	eq(true, errors.Is(err, ErrOverflow))
+	r = NewReader(bytes.NewBuffer(append(long, 0)))
+	_, err = r.ReadSLEB128()
+	eq(true, errors.Is(err, ErrOverflow))
+
+	tooBig := append(bytes.Repeat([]byte{0xff}, 9), 0x02)
+	r = NewReader(bytes.NewBuffer(tooBig))
+	_, err = r.ReadUvarint()
+	eq(true, errors.Is(err, ErrOverflow))
+	r = NewReader(bytes.NewBuffer(tooBig))
+	_, err = r.ReadVarint()
+	eq(true, errors.Is(err, ErrOverflow))
+	r = NewReader(bytes.NewBuffer(append(bytes.Repeat([]byte{0xff}, 9), 0x01)))
+	_, err = r.ReadSLEB128()
+	eq(true, errors.Is(err, ErrOverflow))
+	r = NewReader(bytes.NewBuffer(append(bytes.Repeat([]byte{0x80}, 9), 0x7f)))
+	v, err := r.ReadSLEB128()
+	eq(int64(math.MinInt64), v)
+	eq(nil, err)
+
+	r = NewReader(bytes.NewBuffer([]byte{0x80, 0x80}))
+	_, err = r.ReadUvarint()
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+	r = NewReader(bytes.NewBuffer([]byte{0x80}))
+	_, err = r.ReadSLEB128()
+	eq(true, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestVarintTry(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.TryWriteUvarint(1000)
+	w.TryWriteVarint(-1000)
+	w.TryWriteSLEB128(-1000)
+	eq(nil, w.TryError)
+	eq(nil, w.Close())
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(uint64(1000), r.TryReadUvarint())
+	eq(int64(-1000), r.TryReadVarint())
+	eq(int64(-1000), r.TryReadSLEB128())
+	eq(nil, r.TryError)
+	eq(uint64(0), r.TryReadUvarint())
+	eq(io.EOF, r.TryError)
+	eq(int64(0), r.TryReadVarint())
+	eq(int64(0), r.TryReadSLEB128())
+}
+
+func TestVarintCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteBool(true))
+	eq(nil, w.WriteUvarint(300))
+	eq(nil, w.WriteVarint(-1))
+	eq(nil, w.WriteSLEB128(-123456))
+	eq(int64(49), w.BitsCount)
+	w.TryWriteUvarint(1)
+	w.TryWriteVarint(1)
+	w.TryWriteSLEB128(1)
+	eq(nil, w.TryError)
+	eq(int64(73), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(true)(r.ReadBool())
+	expEq(uint64(300))(r.ReadUvarint())
+	expEq(int64(-1))(r.ReadVarint())
+	expEq(int64(-123456))(r.ReadSLEB128())
+	eq(int64(49), r.BitsCount)
+	eq(uint64(1), r.TryReadUvarint())
+	eq(int64(1), r.TryReadVarint())
+	eq(int64(1), r.TryReadSLEB128())
+	eq(nil, r.TryError)
+	eq(int64(73), r.BitsCount)
+}
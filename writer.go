@@ -7,15 +7,12 @@ Writer implementation.
 package bitio
 
 import (
-	"bufio"
+	"encoding/binary"
 	"io"
 )
 
-// An io.Writer and io.ByteWriter at the same time.
-type writerAndByteWriter interface {
-	io.Writer
-	io.ByteWriter
-}
+// writerBufSize is the size of the internal output buffer of Writer.
+const writerBufSize = 4096
 
 // Writer is the bit writer implementation.
 //
@@ -23,31 +20,128 @@ type writerAndByteWriter interface {
 //
 // For convenience, it also implements io.WriterCloser and io.ByteWriter.
 type Writer struct {
-	out       writerAndByteWriter
-	wrapperbw *bufio.Writer // wrapper bufio.Writer if the target does not implement io.ByteWriter
-	cache     byte          // unwritten bits are stored here
-	bits      byte          // number of unwritten bits in cache
+	out io.Writer
+	err error  // first error of writing to out, all subsequent flushes fail with it
+	buf []byte // output buffer, len(buf) bytes are waiting to be written to out
+
+	written int64 // number of bytes written to out
+
+	// acc holds the unwritten bits: in highest-bits-first order in its highest bits,
+	// in least-significant-bit-first order in its lowest bits; other bits are zero.
+	acc  uint64
+	bits uint8 // number of unwritten bits in acc, always less than 64 between calls
+	lsb  bool  // tells if least-significant-bit-first order is used
+
+	// Strict tells if signed writes (e.g. WriteSigned()) return ErrOverflow
+	// for values that do not fit into the requested number of bits,
+	// instead of truncating them.
+	Strict bool
 
 	// TryError holds the first error occurred in TryXXX() methods.
 	TryError error
+
+	// TryPanic tells if TryXXX() methods panic when they store an error in TryError,
+	// so processing can be aborted early. Use Catch() to recover from the panic.
+	TryPanic bool
 }
 
 // NewWriter returns a new Writer using the specified io.Writer as the output.
 //
+// Writer buffers its output, data is written to out in large chunks.
 // Must be closed in order to flush cached data.
 // If you can't or don't want to close it, flushing data can also be forced
 // by calling Align().
+//
+// The returned Writer uses highest-bits-first order, see NewWriterLSB()
+// for least-significant-bit-first order.
 func NewWriter(out io.Writer) *Writer {
-	w := &Writer{}
-	var ok bool
-	w.out, ok = out.(writerAndByteWriter)
-	if !ok {
-		w.wrapperbw = bufio.NewWriter(out)
-		w.out = w.wrapperbw
-	}
+	return &Writer{out: out, buf: make([]byte, 0, writerBufSize)}
+}
+
+// NewWriterLSB returns a new Writer using the specified io.Writer as the output,
+// which uses least-significant-bit-first order.
+//
+// Must be closed in order to flush cached data.
+// If you can't or don't want to close it, flushing data can also be forced
+// by calling Align().
+func NewWriterLSB(out io.Writer) *Writer {
+	w := NewWriter(out)
+	w.lsb = true
 	return w
 }
 
+// bitPos returns the absolute bit offset of the next bit to be written.
+// Flushing buffered data does not change it.
+func (w *Writer) bitPos() int64 {
+	return (w.written+int64(len(w.buf)))*8 + int64(w.bits)
+}
+
+// flush writes the buffered bytes to the output.
+func (w *Writer) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	n, err := w.out.Write(w.buf)
+	w.written += int64(n)
+	if n < len(w.buf) && err == nil {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		// Keep the unwritten bytes
+		w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// emit appends a full 64-bit word of bits to buf, flushing buf first if needed.
+func (w *Writer) emit(word uint64) error {
+	if len(w.buf)+8 > cap(w.buf) {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+
+	n := len(w.buf)
+	w.buf = w.buf[:n+8]
+	if w.lsb {
+		binary.LittleEndian.PutUint64(w.buf[n:], word)
+	} else {
+		binary.BigEndian.PutUint64(w.buf[n:], word)
+	}
+	return nil
+}
+
+// drain moves the bits of acc to buf, flushing buf if needed.
+// Must only be called if acc holds whole bytes (w.bits%8 == 0).
+func (w *Writer) drain() error {
+	for w.bits > 0 {
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+
+		var b byte
+		if w.lsb {
+			b = byte(w.acc)
+			w.acc >>= 8
+		} else {
+			b = byte(w.acc >> 56)
+			w.acc <<= 8
+		}
+		w.buf = append(w.buf, b)
+		w.bits -= 8
+	}
+	return nil
+}
+
 // Write writes len(p) bytes (8 * len(p) bits) to the underlying writer.
 //
 // Write implements io.Writer, and gives a byte-level interface to the bit stream.
@@ -55,22 +149,59 @@ func NewWriter(out io.Writer) *Writer {
 // to a byte boundary (else all the individual bytes are spread to multiple bytes).
 // Byte boundary can be ensured by calling Align().
 func (w *Writer) Write(p []byte) (n int, err error) {
-	// w.bits will be the same after writing 8 bits, so we don't need to update that.
-	if w.bits == 0 {
-		return w.out.Write(p)
+	off := w.bitPos()
+	if n, err = w.write(p); err != nil {
+		err = wrapError("Write", int64(len(p))*8, off, err)
+	}
+	return
+}
+
+// write is the implementation of Write(), errors are not wrapped.
+func (w *Writer) write(p []byte) (n int, err error) {
+	if w.bits%8 != 0 {
+		// Unaligned: all bytes are spread to 2 bytes
+		for i, b := range p {
+			if err = w.put(uint64(b), 8); err != nil {
+				return i, err
+			}
+		}
+		return len(p), nil
 	}
 
-	for i, b := range p {
-		if err = w.writeUnalignedByte(b); err != nil {
-			return i, err
+	// Aligned: bytes of acc go first
+	if err = w.drain(); err != nil {
+		return 0, err
+	}
+
+	for len(p) > cap(w.buf)-len(w.buf) && w.err == nil {
+		var m int
+		if len(w.buf) == 0 {
+			// Large write and empty buffer: write directly from p to avoid copy
+			m, w.err = w.out.Write(p)
+			w.written += int64(m)
+			if m < len(p) && w.err == nil {
+				w.err = io.ErrShortWrite
+			}
+		} else {
+			m = copy(w.buf[len(w.buf):cap(w.buf)], p)
+			w.buf = w.buf[:len(w.buf)+m]
+			w.flush()
 		}
+		n += m
+		p = p[m:]
+	}
+	if w.err != nil {
+		return n, w.err
 	}
 
-	return len(p), nil
+	m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+	w.buf = w.buf[:len(w.buf)+m]
+	return n + m, nil
 }
 
 // WriteBits writes out the n lowest bits of r.
 // Bits of r in positions higher than n are ignored.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
 //
 // For example:
 //   err := w.WriteBits(0x1234, 8)
@@ -81,10 +212,15 @@ func (w *Writer) WriteBits(r uint64, n uint8) (err error) {
 	// if r would have bits set at n or higher positions (zero indexed),
 	// WriteBitsUnsafe's implementation could "corrupt" bits in cache.
 	// That is not acceptable. To be on the safe side, mask out higher bits:
-	return w.WriteBitsUnsafe((r & (1<<n - 1)), n)
+	r &= 1<<n - 1
+	if n < 64-w.bits {
+		return w.put(r, n) // Fast path, can't fail
+	}
+	return w.writeBits("WriteBits", r, n)
 }
 
 // WriteBitsUnsafe writes out the n lowest bits of r.
+// n must not be greater than 64, else ErrInvalidBitCount is returned.
 //
 // r must not have bits set at n or higher positions (zero indexed).
 // If r might not satisfy this, a mask must be explicitly applied
@@ -102,91 +238,88 @@ func (w *Writer) WriteBits(r uint64, n uint8) (err error) {
 // Or:
 //   err := w.WriteBits(0x1234, 8)            // bits higher than the 8th are ignored here
 func (w *Writer) WriteBitsUnsafe(r uint64, n uint8) (err error) {
-	// Some optimization, frequent cases
-	newbits := w.bits + n
-	if newbits < 8 {
-		// r fits into cache, no write will occur to out
-		w.cache |= byte(r) << (8 - newbits)
-		w.bits = newbits
-		return nil
+	if n < 64-w.bits {
+		return w.put(r, n) // Fast path, can't fail
 	}
+	return w.writeBits("WriteBitsUnsafe", r, n)
+}
 
-	if newbits > 8 {
-		// cache will be filled, and there will be more bits to write
-		// "Fill cache" and write it out
-		free := 8 - w.bits
-		err = w.out.WriteByte(w.cache | byte(r>>(n-free)))
-		if err != nil {
-			return
-		}
-		n -= free
-		// write out whole bytes
-		for n >= 8 {
-			n -= 8
-			// No need to mask r, converting to byte will mask out higher bits
-			err = w.out.WriteByte(byte(r >> n))
-			if err != nil {
-				return
-			}
-		}
-		// Put remaining into cache
-		if n > 0 {
-			// Note: n < 8 (in case of n=8, 1<<n would overflow byte)
-			w.cache, w.bits = (byte(r)&((1<<n)-1))<<(8-n), n
+// put writes out the n lowest bits of r like WriteBitsUnsafe(), errors are not wrapped.
+func (w *Writer) put(r uint64, n uint8) error {
+	if free := 64 - w.bits; n < free {
+		// Fast path: r fits into acc
+		if w.lsb {
+			w.acc |= r << w.bits
 		} else {
-			w.cache, w.bits = 0, 0
+			w.acc |= r << (free - n)
 		}
+		w.bits += n
 		return nil
 	}
+	return w.push(r, n)
+}
+
+// writeBits is the slow path of the write methods when acc gets full,
+// op is the name of the operation reported in errors.
+func (w *Writer) writeBits(op string, r uint64, n uint8) error {
+	off := w.bitPos()
+	if err := w.push(r, n); err != nil {
+		return wrapError(op, int64(n), off, err)
+	}
+	return nil
+}
 
-	// cache will be filled exactly with the bits to be written
-	bb := w.cache | byte(r)
-	w.cache, w.bits = 0, 0
-	return w.out.WriteByte(bb)
+// push writes out the n lowest bits of r when acc gets full, errors are not wrapped.
+func (w *Writer) push(r uint64, n uint8) error {
+	if n > 64 {
+		return ErrInvalidBitCount
+	}
+	free := 64 - w.bits // n >= free
+	rest := n - free    // bits that go into the next word
+	var word uint64
+	if w.lsb {
+		word = w.acc | r<<w.bits
+		w.acc = r >> free
+	} else {
+		word = w.acc | r>>rest
+		w.acc = r << (64 - rest)
+	}
+	w.bits = rest
+	return w.emit(word)
 }
 
 // WriteByte writes 8 bits.
 //
 // WriteByte implements io.ByteWriter.
 func (w *Writer) WriteByte(b byte) (err error) {
-	// w.bits will be the same after writing 8 bits, so we don't need to update that.
-	if w.bits == 0 {
-		return w.out.WriteByte(b)
+	if w.bits == 0 && len(w.buf) < cap(w.buf) {
+		// Aligned, and there's room in buf
+		w.buf = append(w.buf, b)
+		return nil
 	}
-	return w.writeUnalignedByte(b)
-}
-
-// writeUnalignedByte writes 8 bits which are (may be) unaligned.
-func (w *Writer) writeUnalignedByte(b byte) (err error) {
-	// w.bits will be the same after writing 8 bits, so we don't need to update that.
-	bits := w.bits
-	err = w.out.WriteByte(w.cache | b>>bits)
-	if err != nil {
-		return
+	if 8 < 64-w.bits {
+		return w.put(uint64(b), 8) // Fast path, can't fail
 	}
-	w.cache = (b & (1<<bits - 1)) << (8 - bits)
-	return
+	return w.writeBits("WriteByte", uint64(b), 8)
 }
 
 // WriteBool writes one bit: 1 if param is true, 0 otherwise.
 func (w *Writer) WriteBool(b bool) (err error) {
-	if w.bits == 7 {
+	if w.bits == 63 {
 		if b {
-			err = w.out.WriteByte(w.cache | 1)
-		} else {
-			err = w.out.WriteByte(w.cache)
+			return w.writeBits("WriteBool", 1, 1)
 		}
-		if err != nil {
-			return
-		}
-		w.cache, w.bits = 0, 0
-		return nil
+		return w.writeBits("WriteBool", 0, 1)
 	}
 
-	w.bits++
 	if b {
-		w.cache |= 1 << (8 - w.bits)
+		if w.lsb {
+			w.acc |= 1 << w.bits
+		} else {
+			w.acc |= 1 << (63 - w.bits)
+		}
 	}
+	w.bits++
 	return nil
 }
 
@@ -195,17 +328,23 @@ func (w *Writer) WriteBool(b bool) (err error) {
 // If there are cached bits, they are first written to the output.
 // Returns the number of skipped (unset but still written) bits.
 func (w *Writer) Align() (skipped uint8, err error) {
-	if w.bits > 0 {
-		if err = w.out.WriteByte(w.cache); err != nil {
-			return
-		}
+	off := w.bitPos()
+	if skipped, err = w.align(); err != nil {
+		err = wrapError("Align", 0, off, err)
+	}
+	return
+}
 
-		skipped = 8 - w.bits
-		w.cache, w.bits = 0, 0
+// align is the implementation of Align(), errors are not wrapped.
+func (w *Writer) align() (skipped uint8, err error) {
+	if fraction := w.bits % 8; fraction > 0 {
+		skipped = 8 - fraction
+		w.bits += skipped // bits of acc are zero after the unwritten bits
 	}
-	if w.wrapperbw != nil {
-		err = w.wrapperbw.Flush()
+	if err = w.drain(); err != nil {
+		return
 	}
+	err = w.flush()
 	return
 }
 
@@ -216,6 +355,7 @@ func (w *Writer) Align() (skipped uint8, err error) {
 func (w *Writer) TryWrite(p []byte) (n int) {
 	if w.TryError == nil {
 		n, w.TryError = w.Write(p)
+		w.checkTry()
 	}
 	return
 }
@@ -227,6 +367,7 @@ func (w *Writer) TryWrite(p []byte) (n int) {
 func (w *Writer) TryWriteBits(r uint64, n uint8) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBits(r, n)
+		w.checkTry()
 	}
 }
 
@@ -237,6 +378,7 @@ func (w *Writer) TryWriteBits(r uint64, n uint8) {
 func (w *Writer) TryWriteBitsUnsafe(r uint64, n uint8) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBitsUnsafe(r, n)
+		w.checkTry()
 	}
 }
 
@@ -247,6 +389,7 @@ func (w *Writer) TryWriteBitsUnsafe(r uint64, n uint8) {
 func (w *Writer) TryWriteByte(b byte) {
 	if w.TryError == nil {
 		w.TryError = w.WriteByte(b)
+		w.checkTry()
 	}
 }
 
@@ -257,6 +400,7 @@ func (w *Writer) TryWriteByte(b byte) {
 func (w *Writer) TryWriteBool(b bool) {
 	if w.TryError == nil {
 		w.TryError = w.WriteBool(b)
+		w.checkTry()
 	}
 }
 
@@ -267,6 +411,7 @@ func (w *Writer) TryWriteBool(b bool) {
 func (w *Writer) TryAlign() (skipped uint8) {
 	if w.TryError == nil {
 		skipped, w.TryError = w.Align()
+		w.checkTry()
 	}
 	return
 }
@@ -277,8 +422,9 @@ func (w *Writer) TryAlign() (skipped uint8) {
 // Close implements io.Closer.
 func (w *Writer) Close() (err error) {
 	// Make sure cached bits are flushed:
-	if _, err = w.Align(); err != nil {
-		return
+	off := w.bitPos()
+	if _, err = w.align(); err != nil {
+		return wrapError("Close", 0, off, err)
 	}
 
 	return nil
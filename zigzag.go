@@ -0,0 +1,209 @@
+/*
+
+Zigzag encoding.
+
+*/
+
+package bitio
+
+// ZigzagEncode maps a signed value to an unsigned one using zigzag encoding:
+// 0, -1, 1, -2, 2... are mapped to 0, 1, 2, 3, 4...
+//
+// Values with small magnitude are mapped to small unsigned values,
+// so they can be represented in few bits.
+func ZigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// ZigzagDecode is the inverse of ZigzagEncode().
+func ZigzagDecode(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// ReadZigzag reads n bits and returns them as a zigzag encoded signed value
+// (see ZigzagEncode()).
+func (r *Reader) ReadZigzag(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := r.ReadBits(n)
+	return ZigzagDecode(u), wrapError("ReadZigzag", int64(n), off, err)
+}
+
+// ReadZigzagUE reads a zigzag encoded signed value coded as an
+// unsigned Exponential-Golomb code of order k (see ReadUE()).
+//
+// Note that ReadSE() uses a different mapping of signed values.
+func (r *Reader) ReadZigzagUE(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readUE(r, k)
+	return ZigzagDecode(u), wrapError("ReadZigzagUE", 0, off, err)
+}
+
+// TryReadZigzag tries to read an n-bit zigzag encoded signed value.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadZigzag(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadZigzag(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadZigzag(n)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadZigzagUE tries to read a zigzag encoded signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadZigzagUE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *Reader) TryReadZigzagUE(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadZigzagUE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// ReadZigzag reads n bits and returns them as a zigzag encoded signed value
+// (see ZigzagEncode()), and counts the number of bits read.
+func (r *CountReader) ReadZigzag(n uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := r.ReadBits(n)
+	return ZigzagDecode(u), wrapError("ReadZigzag", int64(n), off, err)
+}
+
+// ReadZigzagUE reads a zigzag encoded signed value coded as an
+// unsigned Exponential-Golomb code of order k (see ReadUE()),
+// and counts the number of bits read.
+//
+// Note that ReadSE() uses a different mapping of signed values.
+func (r *CountReader) ReadZigzagUE(k uint8) (v int64, err error) {
+	off := r.bitPos()
+	u, err := readUE(r, k)
+	return ZigzagDecode(u), wrapError("ReadZigzagUE", 0, off, err)
+}
+
+// TryReadZigzag tries to read an n-bit zigzag encoded signed value.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadZigzag(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadZigzag(n uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadZigzag(n)
+		r.checkTry()
+	}
+	return
+}
+
+// TryReadZigzagUE tries to read a zigzag encoded signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls ReadZigzagUE(),
+// returns the data it provides and stores the error in the TryError field.
+func (r *CountReader) TryReadZigzagUE(k uint8) (v int64) {
+	if r.TryError == nil {
+		v, r.TryError = r.ReadZigzagUE(k)
+		r.checkTry()
+	}
+	return
+}
+
+// WriteZigzag writes v zigzag encoded (see ZigzagEncode()) in n bits.
+//
+// If the encoded value does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *Writer) WriteZigzag(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeZigzag(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteZigzag", int64(n), off, err)
+	}
+	return
+}
+
+// WriteZigzagUE writes v zigzag encoded as an unsigned Exponential-Golomb code
+// of order k (see WriteUE()).
+//
+// Note that WriteSE() uses a different mapping of signed values.
+func (w *Writer) WriteZigzagUE(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeUE(w, ZigzagEncode(v), k); err != nil {
+		err = wrapError("WriteZigzagUE", 0, off, err)
+	}
+	return
+}
+
+// TryWriteZigzag tries to write v zigzag encoded in n bits.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteZigzag(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteZigzag(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteZigzag(v, n)
+		w.checkTry()
+	}
+}
+
+// TryWriteZigzagUE tries to write v as a zigzag encoded signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteZigzagUE(),
+// and stores the error in the TryError field.
+func (w *Writer) TryWriteZigzagUE(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteZigzagUE(v, k)
+		w.checkTry()
+	}
+}
+
+// WriteZigzag writes v zigzag encoded (see ZigzagEncode()) in n bits,
+// and counts the number of bits written.
+//
+// If the encoded value does not fit into n bits, only its lowest n bits are written,
+// or ErrOverflow is returned (and nothing is written) if Strict is set.
+func (w *CountWriter) WriteZigzag(v int64, n uint8) (err error) {
+	off := w.bitPos()
+	if err = writeZigzag(w, v, n, w.Strict); err != nil {
+		err = wrapError("WriteZigzag", int64(n), off, err)
+	}
+	return
+}
+
+// WriteZigzagUE writes v zigzag encoded as an unsigned Exponential-Golomb code
+// of order k (see WriteUE()), and counts the number of bits written.
+//
+// Note that WriteSE() uses a different mapping of signed values.
+func (w *CountWriter) WriteZigzagUE(v int64, k uint8) (err error) {
+	off := w.bitPos()
+	if err = writeUE(w, ZigzagEncode(v), k); err != nil {
+		err = wrapError("WriteZigzagUE", 0, off, err)
+	}
+	return
+}
+
+// TryWriteZigzag tries to write v zigzag encoded in n bits.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteZigzag(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteZigzag(v int64, n uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteZigzag(v, n)
+		w.checkTry()
+	}
+}
+
+// TryWriteZigzagUE tries to write v as a zigzag encoded signed Exponential-Golomb code of order k.
+//
+// If there was a previous TryError, it does nothing. Else it calls WriteZigzagUE(),
+// and stores the error in the TryError field.
+func (w *CountWriter) TryWriteZigzagUE(v int64, k uint8) {
+	if w.TryError == nil {
+		w.TryError = w.WriteZigzagUE(v, k)
+		w.checkTry()
+	}
+}
+
+// writeZigzag writes v zigzag encoded in n bits to w.
+// If strict is true, ErrOverflow is returned if the encoded value does not fit into n bits.
+func writeZigzag(w bitWriter, v int64, n uint8, strict bool) (err error) {
+	u := ZigzagEncode(v)
+	if strict && n < 64 && u>>n != 0 {
+		return ErrOverflow
+	}
+	return w.WriteBits(u, n)
+}
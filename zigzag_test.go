@@ -0,0 +1,142 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/icza/mighty"
+)
+
+func TestZigzagEncode(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	cases := []struct {
+		v int64
+		u uint64
+	}{
+		{0, 0}, {-1, 1}, {1, 2}, {-2, 3}, {2, 4},
+		{math.MaxInt64, math.MaxUint64 - 1}, {math.MinInt64, math.MaxUint64},
+	}
+	for _, c := range cases {
+		eq(c.u, ZigzagEncode(c.v))
+		eq(c.v, ZigzagDecode(c.u))
+	}
+	for i := 0; i < 1000; i++ {
+		v := int64(rand.Uint64())
+		eq(v, ZigzagDecode(ZigzagEncode(v)))
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	eq(nil, w.WriteZigzag(-2, 3))   // 011
+	eq(nil, w.WriteZigzag(2, 3))    // 100
+	eq(nil, w.WriteZigzag(-5, 3))   // 001 (truncated)
+	eq(nil, w.WriteZigzagUE(1, 0))  // 011
+	eq(nil, w.WriteZigzagUE(-1, 0)) // 010
+	eq(nil, w.Close())
+	// 0111 0000 1011 0100
+	eq(true, bytes.Equal(b.Bytes(), []byte{0x70, 0xb4}))
+
+	r := NewReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-2))(r.ReadZigzag(3))
+	expEq(int64(2))(r.ReadZigzag(3))
+	expEq(int64(-1))(r.ReadZigzag(3))
+	expEq(int64(1))(r.ReadZigzagUE(0))
+	expEq(int64(-1))(r.ReadZigzagUE(0))
+}
+
+func TestZigzagWidths(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	type value struct {
+		v int64
+		n uint8
+	}
+	var values []value
+	for n := uint8(1); n <= 64; n++ {
+		min, max := int64(-1)<<(n-1), int64(uint64(1)<<(n-1)-1)
+		values = append(values,
+			value{min, n}, value{max, n}, value{0, n}, value{-1, n},
+			value{rand.Int63() >> (64 - n), n}, value{-rand.Int63() >> (64 - n), n},
+		)
+	}
+
+	for _, lsb := range []bool{false, true} {
+		newWriter, newReader := NewWriter, NewReader
+		if lsb {
+			newWriter, newReader = NewWriterLSB, NewReaderLSB
+		}
+
+		b := &bytes.Buffer{}
+		w := newWriter(b)
+		w.Strict = true
+		for _, v := range values {
+			eq(nil, w.WriteZigzag(v.v, v.n))
+			eq(nil, w.WriteZigzagUE(v.v>>40, v.n%4))
+		}
+		eq(nil, w.Close())
+
+		r := newReader(bytes.NewBuffer(b.Bytes()))
+		for _, v := range values {
+			expEq(v.v)(r.ReadZigzag(v.n))
+			expEq(v.v >> 40)(r.ReadZigzagUE(v.n % 4))
+		}
+	}
+}
+
+func TestZigzagStrict(t *testing.T) {
+	eq := mighty.Eq(t)
+
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	w.Strict = true
+	for n := uint8(1); n < 64; n++ {
+		min, max := int64(-1)<<(n-1), int64(uint64(1)<<(n-1)-1)
+		eq(true, errors.Is(w.WriteZigzag(max+1, n), ErrOverflow))
+		eq(true, errors.Is(w.WriteZigzag(min-1, n), ErrOverflow))
+	}
+	eq(true, errors.Is(w.WriteZigzag(-1, 0), ErrOverflow))
+	w.TryWriteZigzag(4, 3)
+	eq(true, errors.Is(w.TryError, ErrOverflow))
+	eq(nil, w.Close())
+	eq(0, b.Len())
+}
+
+func TestZigzagTryCount(t *testing.T) {
+	eq, expEq := mighty.EqExpEq(t)
+
+	b := &bytes.Buffer{}
+	w := NewCountWriter(b)
+	eq(nil, w.WriteZigzag(-3, 5))
+	eq(nil, w.WriteZigzagUE(-3, 0)) // 00110
+	w.TryWriteZigzag(100, 9)
+	w.TryWriteZigzagUE(100, 2)
+	eq(nil, w.TryError)
+	eq(int64(32), w.BitsCount)
+	eq(nil, w.Close())
+
+	r := NewCountReader(bytes.NewBuffer(b.Bytes()))
+	expEq(int64(-3))(r.ReadZigzag(5))
+	expEq(int64(-3))(r.ReadZigzagUE(0))
+	eq(int64(10), r.BitsCount)
+	eq(int64(100), r.TryReadZigzag(9))
+	eq(int64(100), r.TryReadZigzagUE(2))
+	eq(nil, r.TryError)
+	eq(int64(32), r.BitsCount)
+
+	rr := NewReader(bytes.NewBuffer(b.Bytes()))
+	eq(int64(-3), rr.TryReadZigzag(5))
+	eq(int64(-3), rr.TryReadZigzagUE(0))
+	eq(nil, rr.TryError)
+
+	ww := NewWriter(&bytes.Buffer{})
+	ww.TryWriteZigzagUE(math.MaxInt64, 0)
+	eq(true, errors.Is(ww.TryError, ErrOverflow))
+}